@@ -10,11 +10,18 @@ CREATE TABLE IF NOT EXISTS `users`
     `username`    varchar(50)  NOT NULL COMMENT '用户名',
     `password`    varchar(255) NOT NULL COMMENT '密码（加密后）',
     `nike_name`   varchar(50) DEFAULT NULL COMMENT '昵称',
+    `email`       varchar(100) DEFAULT NULL COMMENT '邮箱',
+    `email_verified` tinyint(1) DEFAULT 0 COMMENT '邮箱是否已验证 0-未验证 1-已验证',
+    `pending_email` varchar(100) DEFAULT NULL COMMENT '待验证的邮箱，验证通过后写入 email',
     `create_time` datetime    DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     `update_time` datetime    DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
     `delete`      tinyint(1)  DEFAULT 0 COMMENT '是否删除 0-未删除 1-已删除',
+    `password_change_time` datetime DEFAULT NULL COMMENT '最近一次修改密码的时间',
+    `totp_secret` varchar(64) DEFAULT NULL COMMENT 'TOTP 密钥',
+    `totp_enabled` tinyint(1) DEFAULT 0 COMMENT '是否开启 TOTP 两步验证 0-未开启 1-已开启',
     PRIMARY KEY (`id`),
-    UNIQUE KEY `uk_username` (`username`)
+    UNIQUE KEY `uk_username` (`username`),
+    UNIQUE KEY `uk_email` (`email`)
 ) ENGINE = InnoDB
   DEFAULT CHARSET = utf8mb4 COMMENT ='用户表';
 
@@ -22,7 +29,9 @@ CREATE TABLE IF NOT EXISTS `permission`
 (
     `id`     bigint(20)   NOT NULL AUTO_INCREMENT COMMENT 'id',
     `permit` varchar(100) NOT NULL COMMENT '权限标识',
-    PRIMARY KEY (`id`)
+    `name`   varchar(50) DEFAULT NULL COMMENT '权限名称',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_permit` (`permit`)
 ) ENGINE = InnoDB
   DEFAULT CHARSET = utf8mb4 COMMENT ='权限表';
 
@@ -32,3 +41,126 @@ CREATE TABLE IF NOT EXISTS `user_permission`
     `permission_id` bigint(20) NOT NULL COMMENT '权限id'
 ) ENGINE = InnoDB
   DEFAULT CHARSET = utf8mb4 COMMENT ='用户-权限关联表';
+
+CREATE TABLE IF NOT EXISTS `role`
+(
+    `id`   bigint(20)  NOT NULL AUTO_INCREMENT COMMENT 'id',
+    `code` varchar(50) NOT NULL COMMENT '角色编码',
+    `name` varchar(50) DEFAULT NULL COMMENT '角色名称',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_code` (`code`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='角色表';
+
+CREATE TABLE IF NOT EXISTS `role_permission`
+(
+    `role_id`       bigint(20) NOT NULL COMMENT '角色id',
+    `permission_id` bigint(20) NOT NULL COMMENT '权限id',
+    PRIMARY KEY (`role_id`, `permission_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='角色-权限关联表';
+
+CREATE TABLE IF NOT EXISTS `user_role`
+(
+    `user_id` bigint(20) NOT NULL COMMENT '用户id',
+    `role_id` bigint(20) NOT NULL COMMENT '角色id',
+    PRIMARY KEY (`user_id`, `role_id`),
+    KEY `idx_role_id` (`role_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='用户-角色关联表';
+
+CREATE TABLE IF NOT EXISTS `user_recovery_code`
+(
+    `id`        bigint(20)  NOT NULL AUTO_INCREMENT COMMENT 'id',
+    `user_id`   bigint(20)  NOT NULL COMMENT '用户id',
+    `code_hash` varchar(64) NOT NULL COMMENT '恢复码摘要',
+    `used_time` datetime DEFAULT NULL COMMENT '使用时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='两步验证恢复码表';
+
+CREATE TABLE IF NOT EXISTS `api_key`
+(
+    `id`             bigint(20)   NOT NULL AUTO_INCREMENT COMMENT 'id',
+    `user_id`        bigint(20)   NOT NULL COMMENT '所属用户id',
+    `name`           varchar(50)  NOT NULL COMMENT '名称',
+    `type`           varchar(20)  NOT NULL COMMENT '类型 personal-个人密钥 service-服务密钥',
+    `prefix`         varchar(20)  NOT NULL COMMENT '密钥前缀，便于识别',
+    `key_hash`       varchar(64)  NOT NULL COMMENT '密钥摘要',
+    `scopes`         varchar(1000) NOT NULL DEFAULT '' COMMENT '授权的权限标识，逗号分隔',
+    `expire_time`    datetime DEFAULT NULL COMMENT '过期时间，为空表示永不过期',
+    `last_used_time` datetime DEFAULT NULL COMMENT '最近使用时间',
+    `revoked`        tinyint(1) DEFAULT 0 COMMENT '是否已吊销 0-否 1-是',
+    `create_time`    datetime DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_key_hash` (`key_hash`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='API 密钥表';
+
+CREATE TABLE IF NOT EXISTS `user_identity`
+(
+    `id`          bigint(20)   NOT NULL AUTO_INCREMENT COMMENT 'id',
+    `user_id`     bigint(20)   NOT NULL COMMENT '用户id',
+    `provider`    varchar(50)  NOT NULL COMMENT '身份提供方名称',
+    `subject`     varchar(255) NOT NULL COMMENT '身份提供方中的用户标识',
+    `email`       varchar(100) DEFAULT NULL COMMENT '身份提供方返回的邮箱',
+    `create_time` datetime DEFAULT CURRENT_TIMESTAMP COMMENT '绑定时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_provider_subject` (`provider`, `subject`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='用户外部身份绑定表';
+
+CREATE TABLE IF NOT EXISTS `oauth_client`
+(
+    `id`            bigint(20)    NOT NULL AUTO_INCREMENT COMMENT 'id',
+    `client_id`     varchar(64)   NOT NULL COMMENT '客户端标识',
+    `secret_hash`   varchar(64)   NOT NULL DEFAULT '' COMMENT '客户端密钥摘要，公开客户端为空',
+    `name`          varchar(50)   NOT NULL COMMENT '应用名称',
+    `redirect_uris` varchar(2000) NOT NULL DEFAULT '' COMMENT '回调地址，逗号分隔',
+    `grant_types`   varchar(200)  NOT NULL COMMENT '允许的授权类型，逗号分隔',
+    `scopes`        varchar(1000) NOT NULL COMMENT '允许申请的 scope，逗号分隔',
+    `create_time`   datetime DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_client_id` (`client_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='OAuth2 客户端表';
+
+CREATE TABLE IF NOT EXISTS `user_passkey`
+(
+    `id`               bigint(20)    NOT NULL AUTO_INCREMENT COMMENT 'id',
+    `user_id`          bigint(20)    NOT NULL COMMENT '用户id',
+    `name`             varchar(50)   NOT NULL COMMENT '名称',
+    `credential_id`    varchar(1400) NOT NULL COMMENT '凭据ID（base64url）',
+    `public_key`       blob          NOT NULL COMMENT 'COSE 格式公钥',
+    `attestation_type` varchar(32)   NOT NULL DEFAULT '' COMMENT '证明类型',
+    `aaguid`           varbinary(16) DEFAULT NULL COMMENT '认证器型号标识',
+    `sign_count`       int unsigned  NOT NULL DEFAULT 0 COMMENT '签名计数器',
+    `transports`       varchar(100)  NOT NULL DEFAULT '' COMMENT '支持的传输方式，逗号分隔',
+    `backup_eligible`  tinyint(1)    DEFAULT 0 COMMENT '是否可同步备份',
+    `backup_state`     tinyint(1)    DEFAULT 0 COMMENT '是否已同步备份',
+    `last_used_time`   datetime DEFAULT NULL COMMENT '最近使用时间',
+    `create_time`      datetime DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_credential_id` (`credential_id`(255)),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='用户通行密钥表';
+
+CREATE TABLE IF NOT EXISTS `impersonation_log`
+(
+    `id`          bigint(20)   NOT NULL AUTO_INCREMENT COMMENT 'id',
+    `actor_id`    bigint(20)   NOT NULL COMMENT '操作人id',
+    `user_id`     bigint(20)   NOT NULL COMMENT '被模拟的用户id',
+    `reason`      varchar(200) NOT NULL COMMENT '原因',
+    `token_id`    varchar(64)  NOT NULL COMMENT '模拟令牌jti',
+    `ip`          varchar(64)  NOT NULL DEFAULT '' COMMENT '操作人IP',
+    `expire_time` datetime     NOT NULL COMMENT '令牌过期时间',
+    `create_time` datetime DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_actor_id` (`actor_id`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='模拟登录审计表';
@@ -4,10 +4,17 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path"
+	"strings"
 	"sync"
 	"time"
 	"users-by-go-example/internal/config"
+	"users-by-go-example/internal/ldapauth"
+	"users-by-go-example/internal/mailer"
+	"users-by-go-example/internal/oidc"
+	"users-by-go-example/internal/permit"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -15,9 +22,13 @@ import (
 
 // content 全局资源管理
 type content struct {
-	Config *config.Config
-	DB     *gorm.DB
-	Redis  *redis.Client
+	Config   *config.Config
+	DB       *gorm.DB
+	Redis    *redis.Client
+	Mailer   mailer.Mailer
+	OIDC     map[string]*oidc.Client // 身份提供方名称 -> OIDC 客户端
+	LDAP     *ldapauth.Directory     // 未启用 LDAP 登录时为 nil
+	WebAuthn *webauthn.WebAuthn      // 未启用通行密钥时为 nil
 }
 
 var (
@@ -32,13 +43,30 @@ func InitAll() {
 		initConfig()
 		initDB()
 		initRedis()
+		initMailer()
+		initOIDC()
+		initLDAP()
+		initWebAuthn()
 	})
 }
 
+// Setup 使用已创建的配置和连接初始化，供测试注入内存数据库、Redis 和邮件发送器
+func Setup(conf *config.Config, db *gorm.DB, rdb *redis.Client, m mailer.Mailer) {
+	instance.Config = conf
+	instance.DB = db
+	instance.Redis = rdb
+	instance.Mailer = m
+	initApiPermits()
+	validateImpersonation()
+	initWebAuthn()
+}
+
 // InitConfig 初始化配置
 func initConfig() {
 	conf := config.Load()
 	instance.Config = conf
+	initApiPermits()
+	validateImpersonation()
 }
 
 // InitDB 初始化数据库连接
@@ -89,6 +117,74 @@ func initRedis() {
 	log.Println("Redis 连接成功")
 }
 
+// InitMailer 初始化邮件发送器
+func initMailer() {
+	m, err := mailer.New(&instance.Config.Mail)
+	if err != nil {
+		log.Fatalf("邮件发送器初始化失败: %v", err)
+	}
+	instance.Mailer = m
+}
+
+// initOIDC 按配置创建 OIDC 客户端，身份提供方元数据在第一次登录时获取
+func initOIDC() {
+	instance.OIDC = make(map[string]*oidc.Client)
+	for i := range instance.Config.OIDC.Providers {
+		conf := &instance.Config.OIDC.Providers[i]
+		if _, exists := instance.OIDC[conf.Name]; exists {
+			log.Fatalf("OIDC 身份提供方名称重复: %s", conf.Name)
+		}
+		client, err := oidc.NewClient(conf, nil)
+		if err != nil {
+			log.Fatalf("OIDC 客户端初始化失败: %v", err)
+		}
+		instance.OIDC[conf.Name] = client
+	}
+}
+
+// initLDAP 登录校验链中包含 ldap 时创建 LDAP 用户目录，目录服务不可用不影响服务启动
+func initLDAP() {
+	for _, name := range instance.Config.Auth.Chain {
+		switch name {
+		case "local":
+		case "ldap":
+			directory, err := ldapauth.NewDirectory(&instance.Config.LDAP, nil)
+			if err != nil {
+				log.Fatalf("LDAP 初始化失败: %v", err)
+			}
+			instance.LDAP = directory
+		default:
+			log.Fatalf("auth.chain 配置错误: 不支持的校验方式 %s", name)
+		}
+	}
+}
+
+// initWebAuthn 配置了 rp-id 时启用通行密钥
+func initWebAuthn() {
+	conf := &instance.Config.WebAuthn
+	if conf.RPID == "" {
+		return
+	}
+
+	timeout := time.Duration(conf.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          conf.RPID,
+		RPDisplayName: conf.RPDisplayName,
+		RPOrigins:     conf.RPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: timeout},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: timeout},
+		},
+	})
+	if err != nil {
+		log.Fatalf("WebAuthn 初始化失败: %v", err)
+	}
+	instance.WebAuthn = wa
+}
+
 // GetConfig 获取配置
 func GetConfig() *config.Config {
 	if instance.Config == nil {
@@ -97,27 +193,96 @@ func GetConfig() *config.Config {
 	return instance.Config
 }
 
-var apiPermitsMap map[string]string = nil
-var getApiPermitsMapOnce sync.Once
+// ApiPermit 接口的权限要求
+type ApiPermit struct {
+	Permits     permit.Expr // 权限表达式，为 nil 表示只要求登录
+	SelfPermits permit.Expr // 操作自己的资源时使用的权限表达式
+	OwnerField  string      // 请求体中表示目标用户 ID 的字段，为空表示不区分资源归属
+}
+
+// apiPermitRule 通配模式的 Api 权限规则
+type apiPermitRule struct {
+	method  string
+	pattern string
+	permit  *ApiPermit
+}
+
+var (
+	apiPermitsMap  map[string]*ApiPermit // method + " " + 路由模板 -> 权限要求
+	apiPermitRules []apiPermitRule       // 含通配符的规则，按配置顺序匹配
+)
+
+// initApiPermits 解析 api_permits 配置：权限表达式在加载时一次性编译，路由模板精确索引，通配模式按顺序保存
+func initApiPermits() {
+	apiPermitsMap = make(map[string]*ApiPermit)
+	apiPermitRules = nil
+
+	for _, item := range instance.Config.ApiPermits {
+		expr, err := permit.Parse(item.Permits)
+		if err != nil {
+			log.Fatalf("api_permits 配置错误 [%s %s]: %v", item.Method, item.Path, err)
+		}
+		selfExpr, err := permit.Parse(item.SelfPermits)
+		if err != nil {
+			log.Fatalf("api_permits 配置错误 [%s %s] self_permits: %v", item.Method, item.Path, err)
+		}
+		if item.SelfPermits != "" && item.OwnerField == "" {
+			log.Fatalf("api_permits 配置错误 [%s %s]: 配置 self_permits 时必须指定 owner_field", item.Method, item.Path)
+		}
+
+		apiPermit := &ApiPermit{
+			Permits:     expr,
+			SelfPermits: selfExpr,
+			OwnerField:  item.OwnerField,
+		}
 
-// GetApiPermitsMap 获取Api权限标识Map
-func GetApiPermitsMap() map[string]string {
-	if apiPermitsMap != nil {
-		return apiPermitsMap
+		if strings.ContainsAny(item.Path, "*?[") {
+			if _, err := path.Match(item.Path, ""); err != nil {
+				log.Fatalf("api_permits 配置错误 [%s %s]: 通配模式无效: %v", item.Method, item.Path, err)
+			}
+			apiPermitRules = append(apiPermitRules, apiPermitRule{
+				method:  item.Method,
+				pattern: item.Path,
+				permit:  apiPermit,
+			})
+			continue
+		}
+		apiPermitsMap[item.Method+" "+item.Path] = apiPermit
 	}
+}
 
-	if instance.Config == nil {
+// validateImpersonation 校验模拟登录允许访问的接口，按路由精确匹配，写成通配模式会导致该接口静默失效
+func validateImpersonation() {
+	for _, route := range instance.Config.Impersonation.AllowedPaths {
+		if !strings.HasPrefix(route, "/") || strings.ContainsAny(route, "*?[") {
+			log.Fatalf("impersonation 配置错误 [%s]: 必须是以 / 开头的完整路由，不支持通配", route)
+		}
+	}
+}
+
+// GetApiPermits 获取接口的权限要求
+// routePath 为 gin 注册的路由模板（ctx.FullPath()，如 /api/v1/users/:id），requestPath 为实际请求路径；
+// 先按路由模板精确匹配，再按通配模式（path.Match 语法，method 为 * 时匹配任意方法）匹配实际路径。
+// 返回 nil 表示该接口未配置权限
+func GetApiPermits(method, routePath, requestPath string) *ApiPermit {
+	if apiPermitsMap == nil {
 		panic("配置未初始化")
 	}
 
-	getApiPermitsMapOnce.Do(func() {
-		apiPermitsMap = make(map[string]string)
-		for _, item := range GetConfig().ApiPermits {
-			apiPermitsMap[item.Method+" "+item.Path] = item.Permits
+	if apiPermit, found := apiPermitsMap[method+" "+routePath]; found {
+		return apiPermit
+	}
+
+	for _, rule := range apiPermitRules {
+		if rule.method != "*" && rule.method != method {
+			continue
 		}
-	})
+		if matched, _ := path.Match(rule.pattern, requestPath); matched {
+			return rule.permit
+		}
+	}
 
-	return apiPermitsMap
+	return nil
 }
 
 // GetDB 获取数据库连接
@@ -136,6 +301,29 @@ func GetRedis() *redis.Client {
 	return instance.Redis
 }
 
+// GetMailer 获取邮件发送器
+func GetMailer() mailer.Mailer {
+	if instance.Mailer == nil {
+		panic("邮件发送器未初始化")
+	}
+	return instance.Mailer
+}
+
+// GetOIDCClient 按名称获取 OIDC 客户端，未配置时返回 nil
+func GetOIDCClient(name string) *oidc.Client {
+	return instance.OIDC[name]
+}
+
+// GetLDAPDirectory 获取 LDAP 用户目录，未启用时返回 nil
+func GetLDAPDirectory() *ldapauth.Directory {
+	return instance.LDAP
+}
+
+// GetWebAuthn 获取 WebAuthn 依赖方，未启用时返回 nil
+func GetWebAuthn() *webauthn.WebAuthn {
+	return instance.WebAuthn
+}
+
 // CloseDB 关闭数据库连接
 func CloseDB() error {
 	if instance.DB != nil {
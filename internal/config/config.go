@@ -10,17 +10,32 @@ import (
 // 定义配置结构体
 
 type Config struct {
-	Server     ServerConfig     `yaml:"server" json:"server"`
-	Database   DatabaseConfig   `yaml:"database" json:"database"`
-	Redis      RedisConfig      `yaml:"redis" json:"redis"`
-	JWT        JWTConfig        `yaml:"jwt" json:"jwt"`
-	WhiteList  []string         `yaml:"white_list" json:"whiteList"`
-	ApiPermits []ApiPermitsItem `yaml:"api_permits" json:"apiPermits"`
-	LoggerConf LoggerConfig     `yaml:"logger" json:"logger"`
+	Server         ServerConfig         `yaml:"server" json:"server"`
+	Database       DatabaseConfig       `yaml:"database" json:"database"`
+	Redis          RedisConfig          `yaml:"redis" json:"redis"`
+	JWT            JWTConfig            `yaml:"jwt" json:"jwt"`
+	WhiteList      []string             `yaml:"white_list" json:"whiteList"`
+	ApiPermits     []ApiPermitsItem     `yaml:"api_permits" json:"apiPermits"`
+	Permission     PermissionConfig     `yaml:"permission" json:"permission"`
+	User           UserConfig           `yaml:"user" json:"user"`
+	Mfa            MfaConfig            `yaml:"mfa" json:"mfa"`
+	Mail           MailConfig           `yaml:"mail" json:"mail"`
+	PwdReset       PwdResetConfig       `yaml:"password_reset" json:"passwordReset"`
+	LoginLock      LoginLockConfig      `yaml:"login_lock" json:"loginLock"`
+	PasswordPolicy PasswordPolicyConfig `yaml:"password_policy" json:"passwordPolicy"`
+	PasswordHash   PasswordHashConfig   `yaml:"password_hash" json:"passwordHash"`
+	OIDC           OIDCConfig           `yaml:"oidc" json:"oidc"`
+	OAuth          OAuthConfig          `yaml:"oauth" json:"oauth"`
+	Auth           AuthConfig           `yaml:"auth" json:"auth"`
+	LDAP           LDAPConfig           `yaml:"ldap" json:"ldap"`
+	WebAuthn       WebAuthnConfig       `yaml:"webauthn" json:"webauthn"`
+	Impersonation  ImpersonationConfig  `yaml:"impersonation" json:"impersonation"`
+	LoggerConf     LoggerConfig         `yaml:"logger" json:"logger"`
 }
 
 type ServerConfig struct {
-	Port int `yaml:"port" json:"port"`
+	Port           int      `yaml:"port" json:"port"`
+	TrustedProxies []string `yaml:"trusted-proxies" json:"trustedProxies"` // 可信反向代理的 IP 或网段，为空时不信任 X-Forwarded-For
 }
 
 type DatabaseConfig struct {
@@ -40,14 +55,167 @@ type RedisConfig struct {
 }
 
 type JWTConfig struct {
-	Secret     string `yaml:"secret" json:"secret"`
-	ExpireTime int    `yaml:"expire-time" json:"expireTime"` // 过期时间（小时）
+	Secret            string         `yaml:"secret" json:"secret"`                         // HS256 共享密钥，未配置 keys 时使用
+	AccessExpireTime  int            `yaml:"access-expire-time" json:"accessExpireTime"`   // 访问令牌过期时间（分钟）
+	RefreshExpireTime int            `yaml:"refresh-expire-time" json:"refreshExpireTime"` // 刷新令牌过期时间（小时）
+	SigningKid        string         `yaml:"signing-kid" json:"signingKid"`                // 当前用于签名的密钥 kid
+	Keys              []JWTKeyConfig `yaml:"keys" json:"keys"`                             // 非对称签名密钥，可同时配置多把用于轮换
+}
+
+type JWTKeyConfig struct {
+	Kid        string `yaml:"kid" json:"kid"`
+	Algorithm  string `yaml:"algorithm" json:"algorithm"`    // RS256 或 EdDSA
+	PrivateKey string `yaml:"private-key" json:"privateKey"` // 私钥 PEM 文件路径，已下线的旧密钥可只保留公钥
+	PublicKey  string `yaml:"public-key" json:"publicKey"`   // 公钥 PEM 文件路径，配置私钥时可省略
 }
 
 type ApiPermitsItem struct {
-	Method  string `yaml:"method" json:"method"`
-	Path    string `yaml:"path" json:"path"`
-	Permits string `yaml:"permits" json:"permits"`
+	Method  string `yaml:"method" json:"method"`   // 请求方法，* 匹配任意方法（仅通配模式）
+	Path    string `yaml:"path" json:"path"`       // gin 路由模板（如 /api/v1/users/:id）或通配模式（如 /api/v1/roles/*）
+	Permits string `yaml:"permits" json:"permits"` // 为空表示只要求登录
+
+	// 资源归属规则：请求体中 owner_field 指定的用户 ID 等于当前用户时，改为校验 self_permits
+	SelfPermits string `yaml:"self_permits" json:"selfPermits"`
+	OwnerField  string `yaml:"owner_field" json:"ownerField"`
+}
+
+type PermissionConfig struct {
+	CacheTTL    int  `yaml:"cache-ttl" json:"cacheTTL"`       // 用户权限缓存时间（秒），0 表示不缓存
+	DefaultDeny bool `yaml:"default-deny" json:"defaultDeny"` // 需要认证但未配置 api_permits 的接口是否拒绝访问，关闭时只有拥有 * 的用户可以访问
+}
+
+type UserConfig struct {
+	RequireVerifiedEmail bool   `yaml:"require_verified_email" json:"requireVerifiedEmail"` // 邮箱未验证的用户禁止登录
+	EmailVerifyTTL       int    `yaml:"email-verify-ttl" json:"emailVerifyTTL"`             // 邮箱验证令牌有效期（小时）
+	EmailVerifyURL       string `yaml:"email-verify-url" json:"emailVerifyURL"`             // 邮件中的验证链接，%s 替换为验证令牌
+}
+
+type MfaConfig struct {
+	Issuer     string `yaml:"issuer" json:"issuer"`          // 验证器 App 中显示的服务名称
+	PendingTTL int    `yaml:"pending-ttl" json:"pendingTTL"` // 两步验证待完成令牌有效期（分钟）
+}
+
+type MailConfig struct {
+	Driver  string     `yaml:"driver" json:"driver"`    // log 或 smtp
+	From    string     `yaml:"from" json:"from"`        // 发件人地址
+	LogFile string     `yaml:"log-file" json:"logFile"` // log 驱动的输出文件，为空时输出到标准错误
+	SMTP    SMTPConfig `yaml:"smtp" json:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" json:"host"`
+	Port     int    `yaml:"port" json:"port"`
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+}
+
+type PwdResetConfig struct {
+	TokenTTL     int    `yaml:"token-ttl" json:"tokenTTL"`         // 重置令牌有效期（分钟）
+	URL          string `yaml:"url" json:"url"`                    // 邮件中的重置链接，%s 替换为重置令牌
+	AccountLimit int    `yaml:"account-limit" json:"accountLimit"` // 每个账号每小时最多发起次数
+	IPLimit      int    `yaml:"ip-limit" json:"ipLimit"`           // 每个 IP 每小时最多发起次数
+}
+
+type PasswordPolicyConfig struct {
+	MinLength        int    `yaml:"min-length" json:"minLength"`               // 最小长度，0 表示不限制
+	MaxLength        int    `yaml:"max-length" json:"maxLength"`               // 最大长度，0 表示不限制
+	RequireLower     bool   `yaml:"require-lower" json:"requireLower"`         // 必须包含小写字母
+	RequireUpper     bool   `yaml:"require-upper" json:"requireUpper"`         // 必须包含大写字母
+	RequireDigit     bool   `yaml:"require-digit" json:"requireDigit"`         // 必须包含数字
+	RequireSymbol    bool   `yaml:"require-symbol" json:"requireSymbol"`       // 必须包含特殊字符
+	DisallowUsername bool   `yaml:"disallow-username" json:"disallowUsername"` // 不能包含用户名
+	BlocklistFile    string `yaml:"blocklist-file" json:"blocklistFile"`       // 常见/泄露密码黑名单文件
+}
+
+type PasswordHashConfig struct {
+	Algorithm string         `yaml:"algorithm" json:"algorithm"` // argon2id | bcrypt，新密码使用该算法
+	Argon2id  Argon2idConfig `yaml:"argon2id" json:"argon2id"`
+	Bcrypt    BcryptConfig   `yaml:"bcrypt" json:"bcrypt"`
+}
+
+type Argon2idConfig struct {
+	Memory      uint32 `yaml:"memory" json:"memory"` // 内存（KiB）
+	Iterations  uint32 `yaml:"iterations" json:"iterations"`
+	Parallelism uint8  `yaml:"parallelism" json:"parallelism"`
+	SaltLength  uint32 `yaml:"salt-length" json:"saltLength"` // 盐长度（字节）
+	KeyLength   uint32 `yaml:"key-length" json:"keyLength"`   // 哈希长度（字节）
+	// MaxConcurrency 同时进行的 argon2id 计算数上限，超出的请求排队等待，避免并发登录耗尽内存；0 表示 CPU 核数
+	MaxConcurrency int `yaml:"max-concurrency" json:"maxConcurrency"`
+}
+
+type BcryptConfig struct {
+	Cost int `yaml:"cost" json:"cost"`
+}
+
+type OIDCConfig struct {
+	StateTTL  int                  `yaml:"state-ttl" json:"stateTTL"` // 发起登录到回调的最长时间（分钟）
+	Providers []OIDCProviderConfig `yaml:"providers" json:"providers"`
+}
+
+type OIDCProviderConfig struct {
+	Name          string   `yaml:"name" json:"name"` // 登录接口中使用的身份提供方名称
+	Issuer        string   `yaml:"issuer" json:"issuer"`
+	ClientID      string   `yaml:"client-id" json:"clientId"`
+	ClientSecret  string   `yaml:"client-secret" json:"clientSecret"`
+	RedirectURL   string   `yaml:"redirect-url" json:"redirectUrl"` // 前端回调页面，需在身份提供方登记
+	Scopes        []string `yaml:"scopes" json:"scopes"`
+	AutoProvision bool     `yaml:"auto-provision" json:"autoProvision"` // 首次登录时自动创建本地用户
+	UsernameClaim string   `yaml:"username-claim" json:"usernameClaim"` // 自动创建用户时用作用户名的声明，默认 preferred_username
+}
+
+type OAuthConfig struct {
+	Issuer         string `yaml:"issuer" json:"issuer"`                   // 作为授权服务器对外的地址，写入 ID Token 的 iss
+	AuthorizeURL   string `yaml:"authorize-url" json:"authorizeURL"`      // 前端授权确认页地址，即对外公布的 authorization_endpoint
+	CodeTTL        int    `yaml:"code-ttl" json:"codeTTL"`                // 授权码有效期（分钟）
+	AccessTokenTTL int    `yaml:"access-token-ttl" json:"accessTokenTTL"` // 访问令牌有效期（分钟）
+}
+
+type AuthConfig struct {
+	Chain []string `yaml:"chain" json:"chain"` // 登录时依次尝试的凭据校验方式：ldap、local，默认只有 local
+}
+
+type LDAPConfig struct {
+	URL                  string             `yaml:"url" json:"url"` // ldap://host:389 或 ldaps://host:636
+	StartTLS             bool               `yaml:"start-tls" json:"startTLS"`
+	InsecureSkipVerify   bool               `yaml:"insecure-skip-verify" json:"insecureSkipVerify"`
+	Timeout              int                `yaml:"timeout" json:"timeout"` // 连接和查询超时（秒）
+	BindDN               string             `yaml:"bind-dn" json:"bindDN"`  // 用于查找用户的服务账号
+	BindPassword         string             `yaml:"bind-password" json:"bindPassword"`
+	BaseDN               string             `yaml:"base-dn" json:"baseDN"`
+	UserFilter           string             `yaml:"user-filter" json:"userFilter"` // 其中的 %s 均替换为转义后的登录名
+	UsernameAttribute    string             `yaml:"username-attribute" json:"usernameAttribute"`
+	EmailAttribute       string             `yaml:"email-attribute" json:"emailAttribute"`
+	DisplayNameAttribute string             `yaml:"display-name-attribute" json:"displayNameAttribute"`
+	GroupBaseDN          string             `yaml:"group-base-dn" json:"groupBaseDN"`
+	GroupFilter          string             `yaml:"group-filter" json:"groupFilter"` // %s 替换为转义后的用户 DN，为空时只读取用户的 memberOf
+	GroupMappings        []LDAPGroupMapping `yaml:"group-mappings" json:"groupMappings"`
+}
+
+// LDAPGroupMapping LDAP 组到本地角色和权限的映射，group 可以是组的 DN 或 CN
+type LDAPGroupMapping struct {
+	Group   string   `yaml:"group" json:"group"`
+	Roles   []string `yaml:"roles" json:"roles"`     // 角色编码
+	Permits []string `yaml:"permits" json:"permits"` // 权限标识
+}
+
+type WebAuthnConfig struct {
+	RPID          string   `yaml:"rp-id" json:"rpId"`                    // 依赖方 ID，一般为前端域名，为空表示不启用通行密钥
+	RPDisplayName string   `yaml:"rp-display-name" json:"rpDisplayName"` // 认证器上显示的服务名称
+	RPOrigins     []string `yaml:"rp-origins" json:"rpOrigins"`          // 允许发起注册和登录的前端源
+	Timeout       int      `yaml:"timeout" json:"timeout"`               // 注册和登录仪式的有效期（秒）
+}
+
+type ImpersonationConfig struct {
+	TTL          int      `yaml:"ttl" json:"ttl"`                    // 模拟令牌有效期（分钟）
+	AllowedPaths []string `yaml:"allowed-paths" json:"allowedPaths"` // 模拟期间允许访问的接口，按路由精确匹配，未列出的一律拒绝
+}
+
+type LoginLockConfig struct {
+	MaxFailures     int `yaml:"max-failures" json:"maxFailures"`          // 同一账号窗口期内允许的失败次数，0 表示不限制
+	IPMaxFailures   int `yaml:"ip-max-failures" json:"ipMaxFailures"`     // 同一 IP 窗口期内允许的失败次数，0 表示不限制
+	FailureWindow   int `yaml:"failure-window" json:"failureWindow"`      // 失败计数窗口（分钟）
+	LockDuration    int `yaml:"lock-duration" json:"lockDuration"`        // 首次锁定时长（分钟），之后每次翻倍
+	MaxLockDuration int `yaml:"max-lock-duration" json:"maxLockDuration"` // 锁定时长上限（分钟）
 }
 
 type LoggerConfig struct {
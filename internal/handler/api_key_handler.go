@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+	"users-by-go-example/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ApiKeyHandler API 密钥处理器
+type ApiKeyHandler struct {
+	apiKeyService *service.ApiKeyService
+}
+
+// NewApiKeyHandler 创建 API 密钥处理器
+func NewApiKeyHandler() *ApiKeyHandler {
+	return &ApiKeyHandler{
+		apiKeyService: &service.ApiKeyService{},
+	}
+}
+
+// CreateMine 为当前用户创建个人密钥
+func (h *ApiKeyHandler) CreateMine(ctx *gin.Context) {
+	var params model.CreateApiKeyRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	key, err := h.apiKeyService.Create(ctx.GetInt64("userId"), model.ApiKeyTypePersonal, &params)
+	if err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "创建成功，请妥善保存密钥，关闭后将无法再次查看", key)
+}
+
+// ListMine 查询当前用户的密钥
+func (h *ApiKeyHandler) ListMine(ctx *gin.Context) {
+	keys, err := h.apiKeyService.List(ctx.GetInt64("userId"))
+	if err != nil {
+		InternalError(ctx, "查询失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", keys)
+}
+
+// RevokeMine 吊销当前用户的密钥
+func (h *ApiKeyHandler) RevokeMine(ctx *gin.Context) {
+	var params model.RevokeApiKeyRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(params.ID, ctx.GetInt64("userId")); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "吊销成功", nil)
+}
+
+// Create 管理员为服务账号创建服务密钥
+func (h *ApiKeyHandler) Create(ctx *gin.Context) {
+	var params model.CreateApiKeyRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+	if params.UserId == 0 {
+		BadRequest(ctx, "参数错误: 请指定服务账号 userId")
+		return
+	}
+
+	key, err := h.apiKeyService.Create(params.UserId, model.ApiKeyTypeService, &params)
+	if err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	logger.GetLogger(ctx).Info("创建服务密钥 id=%d userId=%d operator=%v", key.ID, params.UserId, ctx.GetInt64("userId"))
+
+	Success(ctx, "创建成功，请妥善保存密钥，关闭后将无法再次查看", key)
+}
+
+// List 查询密钥，userId 为空时查询全部
+func (h *ApiKeyHandler) List(ctx *gin.Context) {
+	var params model.ListApiKeysRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	keys, err := h.apiKeyService.List(params.UserId)
+	if err != nil {
+		InternalError(ctx, "查询失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", keys)
+}
+
+// Revoke 吊销任意密钥
+func (h *ApiKeyHandler) Revoke(ctx *gin.Context) {
+	var params model.RevokeApiKeyRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(params.ID, 0); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	logger.GetLogger(ctx).Info("吊销 API Key id=%d operator=%v", params.ID, ctx.GetInt64("userId"))
+
+	Success(ctx, "吊销成功", nil)
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+	"users-by-go-example/logger"
+	"users-by-go-example/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonationHandler 模拟登录处理器
+type ImpersonationHandler struct {
+	impersonationService *service.ImpersonationService
+}
+
+// NewImpersonationHandler 创建模拟登录处理器
+func NewImpersonationHandler() *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: &service.ImpersonationService{},
+	}
+}
+
+// Start 以指定用户的身份签发模拟令牌，结束模拟时使用该令牌调用 /logout
+func (h *ImpersonationHandler) Start(ctx *gin.Context) {
+	var params model.ImpersonateRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	// 模拟登录必须由真实登录的用户发起，API Key 和模拟令牌都不能再发起模拟
+	value, exists := ctx.Get("claims")
+	if !exists {
+		Unauthorized(ctx, "模拟登录需要使用登录令牌")
+		return
+	}
+	claims := value.(*utils.Claims)
+	if claims.Actor != nil {
+		BadRequest(ctx, "模拟登录期间不能再次模拟")
+		return
+	}
+
+	resp, err := h.impersonationService.Start(claims.UserID, claims.Username, &params, ctx.ClientIP())
+	if err != nil {
+		BadRequestError(ctx, err)
+		return
+	}
+
+	logger.GetLogger(ctx).Warn("开始模拟登录 actor=%d(%s) userId=%d reason=%s", claims.UserID, claims.Username, params.UserId, params.Reason)
+
+	Success(ctx, "模拟登录成功", resp)
+}
+
+// ListLogs 查询模拟登录审计记录
+func (h *ImpersonationHandler) ListLogs(ctx *gin.Context) {
+	var params model.ListImpersonationLogsRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	page := params.Page
+	pageSize := params.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	logs, total, err := h.impersonationService.ListLogs(&params, page, pageSize)
+	if err != nil {
+		InternalError(ctx, "查询失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", PageResponse{
+		List:     logs,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
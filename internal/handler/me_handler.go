@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MeHandler 当前登录用户的自助操作处理器
+type MeHandler struct {
+	userService  *service.UserService
+	emailService *service.EmailService
+}
+
+// NewMeHandler 创建当前用户处理器
+func NewMeHandler() *MeHandler {
+	return &MeHandler{
+		userService:  &service.UserService{},
+		emailService: &service.EmailService{},
+	}
+}
+
+// GetProfile 获取当前用户资料
+func (h *MeHandler) GetProfile(ctx *gin.Context) {
+	user, err := h.userService.GetUserByID(ctx.GetInt64("userId"))
+	if err != nil {
+		NotFound(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", user)
+}
+
+// UpdateProfile 修改当前用户昵称
+func (h *MeHandler) UpdateProfile(ctx *gin.Context) {
+	var params model.UpdateProfileRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	userId := ctx.GetInt64("userId")
+	user, err := h.userService.UpdateUser(userId, &model.UpdateUserRequest{
+		ID:       userId,
+		NikeName: params.NikeName,
+	})
+	if err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "更新成功", user)
+}
+
+// ChangeEmail 修改当前用户邮箱，并向新邮箱发送验证邮件
+func (h *MeHandler) ChangeEmail(ctx *gin.Context) {
+	var params model.ChangeEmailRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.emailService.ChangeEmail(ctx.GetInt64("userId"), params.Email); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "邮箱已修改，请查收验证邮件", nil)
+}
+
+// SendEmailVerification 重新发送邮箱验证邮件
+func (h *MeHandler) SendEmailVerification(ctx *gin.Context) {
+	if err := h.emailService.SendVerification(ctx.GetInt64("userId")); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "验证邮件已发送", nil)
+}
+
+// ChangePassword 修改当前用户密码，成功后旧令牌全部失效并返回新的令牌对
+func (h *MeHandler) ChangePassword(ctx *gin.Context) {
+	var params model.ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	tokens, err := h.userService.ChangePassword(ctx.GetInt64("userId"), &params, clientInfo(ctx))
+	if err != nil {
+		BadRequestError(ctx, err)
+		return
+	}
+
+	Success(ctx, "密码修改成功", tokens)
+}
+
+// Deactivate 注销当前账号
+func (h *MeHandler) Deactivate(ctx *gin.Context) {
+	if err := h.userService.Deactivate(ctx.GetInt64("userId")); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "账号已注销", nil)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MfaHandler 两步验证处理器
+type MfaHandler struct {
+	mfaService *service.MfaService
+}
+
+// NewMfaHandler 创建两步验证处理器
+func NewMfaHandler() *MfaHandler {
+	return &MfaHandler{
+		mfaService: &service.MfaService{},
+	}
+}
+
+// Enroll 生成 TOTP 密钥和绑定二维码链接
+func (h *MfaHandler) Enroll(ctx *gin.Context) {
+	resp, err := h.mfaService.Enroll(ctx.GetInt64("userId"))
+	if err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "请使用验证器 App 扫码绑定", resp)
+}
+
+// Confirm 提交验证码确认绑定，返回恢复码
+func (h *MfaHandler) Confirm(ctx *gin.Context) {
+	var params model.TotpConfirmRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	codes, err := h.mfaService.Confirm(ctx.GetInt64("userId"), params.Code)
+	if err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "两步验证已开启，请妥善保存恢复码", &model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable 关闭两步验证
+func (h *MfaHandler) Disable(ctx *gin.Context) {
+	var params model.TotpDisableRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.mfaService.Disable(ctx.GetInt64("userId"), &params); err != nil {
+		if errors.Is(err, service.ErrMfaTooManyAttempts) {
+			TooManyRequests(ctx, err.Error())
+			return
+		}
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "两步验证已关闭", nil)
+}
+
+// VerifyLogin 登录第二步：提交验证码或恢复码换取令牌
+func (h *MfaHandler) VerifyLogin(ctx *gin.Context) {
+	var params model.MfaLoginRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	tokens, err := h.mfaService.VerifyLogin(&params, clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, service.ErrMfaTooManyAttempts) || errors.Is(err, service.ErrLoginLocked) {
+			TooManyRequests(ctx, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrMfaTokenInvalid) || errors.Is(err, service.ErrMfaCodeInvalid) {
+			Unauthorized(ctx, err.Error())
+			return
+		}
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "登录成功", tokens)
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+	"users-by-go-example/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthHandler OAuth2 / OIDC 授权服务器处理器
+// token、introspect、userinfo 供第三方应用调用，按 RFC 格式直接返回，不包装统一响应结构
+type OAuthHandler struct {
+	oauthService *service.OAuthService
+}
+
+// NewOAuthHandler 创建授权服务器处理器
+func NewOAuthHandler() *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: &service.OAuthService{},
+	}
+}
+
+// CreateClient 注册第三方应用
+func (h *OAuthHandler) CreateClient(ctx *gin.Context) {
+	var params model.CreateOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	client, err := h.oauthService.CreateClient(&params)
+	if err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "创建成功，请妥善保存客户端密钥，之后将无法再次查看", client)
+}
+
+// ListClients 获取第三方应用列表
+func (h *OAuthHandler) ListClients(ctx *gin.Context) {
+	clients, err := h.oauthService.ListClients()
+	if err != nil {
+		InternalError(ctx, "查询失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", clients)
+}
+
+// DeleteClient 删除第三方应用
+func (h *OAuthHandler) DeleteClient(ctx *gin.Context) {
+	var params model.DeleteOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.oauthService.DeleteClient(params.ID); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "删除成功", nil)
+}
+
+// Authorize 当前用户在前端授权确认页同意后签发授权码，前端跳转到返回的回调地址
+func (h *OAuthHandler) Authorize(ctx *gin.Context) {
+	var params model.OAuthAuthorizeRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	result, err := h.oauthService.Authorize(ctx.GetInt64("userId"), &params)
+	if err != nil {
+		var oauthErr *service.OAuthError
+		if errors.As(err, &oauthErr) {
+			BadRequest(ctx, oauthErr.Description)
+			return
+		}
+		logger.GetLogger(ctx).Error("签发授权码失败: %v", err)
+		InternalError(ctx, "授权失败")
+		return
+	}
+
+	Success(ctx, "授权成功", result)
+}
+
+// Token 令牌接口（表单参数），支持 authorization_code 和 client_credentials
+func (h *OAuthHandler) Token(ctx *gin.Context) {
+	client, err := h.authenticateClient(ctx)
+	if err != nil {
+		h.oauthError(ctx, err)
+		return
+	}
+
+	var tokens *model.OAuthTokenResponse
+	switch ctx.PostForm("grant_type") {
+	case model.GrantTypeAuthorizationCode:
+		tokens, err = h.oauthService.ExchangeCode(client, ctx.PostForm("code"), ctx.PostForm("redirect_uri"), ctx.PostForm("code_verifier"))
+	case model.GrantTypeClientCredentials:
+		tokens, err = h.oauthService.ClientCredentials(client, ctx.PostForm("scope"))
+	default:
+		err = &service.OAuthError{Code: "unsupported_grant_type", Description: "不支持的 grant_type"}
+	}
+	if err != nil {
+		h.oauthError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// Introspect 令牌内省（RFC 7662），调用方必须是已注册的机密客户端
+func (h *OAuthHandler) Introspect(ctx *gin.Context) {
+	client, err := h.authenticateClient(ctx)
+	if err == nil && client.Public() {
+		err = &service.OAuthError{Code: "invalid_client", Description: "公开客户端不能调用内省接口"}
+	}
+	if err != nil {
+		h.oauthError(ctx, err)
+		return
+	}
+
+	result, err := h.oauthService.Introspect(ctx.PostForm("token"))
+	if err != nil {
+		h.oauthError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// UserInfo OIDC userinfo 接口，使用 Authorization: Bearer 传递访问令牌
+func (h *OAuthHandler) UserInfo(ctx *gin.Context) {
+	token, found := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if !found {
+		token = ""
+	}
+
+	info, err := h.oauthService.UserInfo(strings.TrimSpace(token))
+	if err != nil {
+		h.oauthError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, info)
+}
+
+// authenticateClient 优先使用 HTTP Basic 认证，其次读取表单中的 client_id、client_secret
+func (h *OAuthHandler) authenticateClient(ctx *gin.Context) (*model.OAuthClient, error) {
+	clientId, clientSecret, ok := ctx.Request.BasicAuth()
+	if !ok {
+		clientId, clientSecret = ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	}
+	if clientId == "" {
+		return nil, &service.OAuthError{Code: "invalid_client", Description: "缺少客户端认证信息"}
+	}
+	return h.oauthService.AuthenticateClient(clientId, clientSecret)
+}
+
+// oauthError 按 RFC 6749 5.2 返回错误，内部错误只记日志
+func (h *OAuthHandler) oauthError(ctx *gin.Context, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		logger.GetLogger(ctx).Error("授权服务器内部错误: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch oauthErr.Code {
+	case "invalid_client":
+		status = http.StatusUnauthorized
+		ctx.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	case "invalid_token":
+		status = http.StatusUnauthorized
+		ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	ctx.JSON(status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+}
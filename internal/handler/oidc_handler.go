@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+	"users-by-go-example/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCHandler 单点登录处理器
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+// NewOIDCHandler 创建单点登录处理器
+func NewOIDCHandler() *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: &service.OIDCService{},
+	}
+}
+
+// Login 发起单点登录，返回身份提供方的授权地址，由前端跳转
+func (h *OIDCHandler) Login(ctx *gin.Context) {
+	var params model.OIDCLoginRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	resp, err := h.oidcService.Start(params.Provider)
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCProviderNotFound) {
+			BadRequest(ctx, err.Error())
+			return
+		}
+		logger.GetLogger(ctx).Error("发起单点登录失败: %v", err)
+		InternalError(ctx, "发起单点登录失败")
+		return
+	}
+
+	Success(ctx, "请跳转到身份提供方登录", resp)
+}
+
+// Callback 身份提供方回调前端后，由前端提交 code、state 和发起登录时保存的 binding 完成登录
+func (h *OIDCHandler) Callback(ctx *gin.Context) {
+	var params model.OIDCCallbackRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	tokens, err := h.oidcService.Callback(&params, clientInfo(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrOIDCStateInvalid), errors.Is(err, service.ErrOIDCProviderNotFound):
+			BadRequest(ctx, err.Error())
+		case errors.Is(err, service.ErrOIDCUserNotLinked):
+			Unauthorized(ctx, err.Error())
+		default:
+			// 令牌交换或 ID Token 校验失败的细节只记日志
+			logger.GetLogger(ctx).Error("单点登录失败: %v", err)
+			Unauthorized(ctx, "单点登录失败")
+		}
+		return
+	}
+
+	Success(ctx, "登录成功", tokens)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+	"users-by-go-example/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasskeyHandler 通行密钥处理器
+type PasskeyHandler struct {
+	passkeyService *service.PasskeyService
+}
+
+// NewPasskeyHandler 创建通行密钥处理器
+func NewPasskeyHandler() *PasskeyHandler {
+	return &PasskeyHandler{
+		passkeyService: &service.PasskeyService{},
+	}
+}
+
+// Register 确认身份后生成注册选项，前端传给 navigator.credentials.create()
+func (h *PasskeyHandler) Register(ctx *gin.Context) {
+	var params model.PasskeyRegisterRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	resp, err := h.passkeyService.BeginRegistration(ctx.GetInt64("userId"), &params)
+	if err != nil {
+		if errors.Is(err, service.ErrMfaTooManyAttempts) || errors.Is(err, service.ErrStepUpTooManyAttempts) {
+			TooManyRequests(ctx, err.Error())
+			return
+		}
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "请在设备上完成验证", resp)
+}
+
+// Confirm 提交认证器返回的注册结果，保存通行密钥
+func (h *PasskeyHandler) Confirm(ctx *gin.Context) {
+	var params model.PasskeyConfirmRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	passkey, err := h.passkeyService.FinishRegistration(ctx.GetInt64("userId"), &params)
+	if err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "通行密钥已添加", passkey)
+}
+
+// List 列出当前用户的通行密钥
+func (h *PasskeyHandler) List(ctx *gin.Context) {
+	passkeys, err := h.passkeyService.List(ctx.GetInt64("userId"))
+	if err != nil {
+		InternalError(ctx, "查询失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", passkeys)
+}
+
+// Delete 删除当前用户的通行密钥
+func (h *PasskeyHandler) Delete(ctx *gin.Context) {
+	var params model.DeletePasskeyRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.passkeyService.Delete(ctx.GetInt64("userId"), params.ID); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "删除成功", nil)
+}
+
+// LoginOptions 无密码登录第一步：生成登录选项，前端传给 navigator.credentials.get()
+func (h *PasskeyHandler) LoginOptions(ctx *gin.Context) {
+	resp, err := h.passkeyService.BeginLogin()
+	if err != nil {
+		if errors.Is(err, service.ErrPasskeyDisabled) {
+			BadRequest(ctx, err.Error())
+			return
+		}
+		logger.GetLogger(ctx).Error("生成通行密钥登录选项失败: %v", err)
+		InternalError(ctx, "登录失败")
+		return
+	}
+
+	Success(ctx, "请在设备上完成验证", resp)
+}
+
+// Login 无密码登录第二步：提交认证器返回的断言换取令牌
+func (h *PasskeyHandler) Login(ctx *gin.Context) {
+	var params model.PasskeyLoginRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	tokens, err := h.passkeyService.FinishLogin(&params, clientInfo(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPasskeyDisabled):
+			BadRequest(ctx, err.Error())
+		case errors.Is(err, service.ErrLoginLocked):
+			TooManyRequests(ctx, err.Error())
+		case errors.Is(err, service.ErrPasskeyInvalid), errors.Is(err, service.ErrPasskeySessionExpire), errors.Is(err, service.ErrEmailNotVerified):
+			Unauthorized(ctx, err.Error())
+		default:
+			logger.GetLogger(ctx).Error("通行密钥登录失败: %v", err)
+			InternalError(ctx, "登录失败")
+		}
+		return
+	}
+
+	Success(ctx, "登录成功", tokens)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+	"users-by-go-example/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordResetHandler 找回密码处理器
+type PasswordResetHandler struct {
+	passwordResetService *service.PasswordResetService
+}
+
+// NewPasswordResetHandler 创建找回密码处理器
+func NewPasswordResetHandler() *PasswordResetHandler {
+	return &PasswordResetHandler{
+		passwordResetService: &service.PasswordResetService{},
+	}
+}
+
+// RequestReset 申请重置密码
+func (h *PasswordResetHandler) RequestReset(ctx *gin.Context) {
+	var params model.PasswordResetRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.passwordResetService.RequestReset(&params, ctx.ClientIP()); err != nil {
+		if errors.Is(err, service.ErrTooManyResetRequests) {
+			TooManyRequests(ctx, err.Error())
+			return
+		}
+		logger.GetLogger(ctx).Error("发送重置密码邮件失败: %v", err)
+		InternalError(ctx, "发送失败，请稍后重试")
+		return
+	}
+
+	Success(ctx, "如果账号存在，重置密码邮件已发送", nil)
+}
+
+// ConfirmReset 确认重置密码
+func (h *PasswordResetHandler) ConfirmReset(ctx *gin.Context) {
+	var params model.PasswordResetConfirmRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.passwordResetService.ConfirmReset(&params); err != nil {
+		BadRequestError(ctx, err)
+		return
+	}
+
+	Success(ctx, "密码已重置，请重新登录", nil)
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionHandler 权限处理器
+type PermissionHandler struct {
+	permissionService *service.PermissionService
+}
+
+// NewPermissionHandler 创建权限处理器
+func NewPermissionHandler() *PermissionHandler {
+	return &PermissionHandler{
+		permissionService: &service.PermissionService{},
+	}
+}
+
+// ListPermissions 获取权限列表
+func (h *PermissionHandler) ListPermissions(ctx *gin.Context) {
+	permissions, err := h.permissionService.ListPermissions()
+	if err != nil {
+		InternalError(ctx, "查询失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", permissions)
+}
+
+// CreatePermission 创建权限
+func (h *PermissionHandler) CreatePermission(ctx *gin.Context) {
+	var params model.CreatePermissionRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	permission, err := h.permissionService.CreatePermission(&params)
+	if err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "创建成功", permission)
+}
+
+// DeletePermission 删除权限
+func (h *PermissionHandler) DeletePermission(ctx *gin.Context) {
+	var params model.DeletePermissionRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.permissionService.DeletePermission(params.ID); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "删除成功", nil)
+}
+
+// ListUserPermissions 获取直接授予用户的权限
+func (h *PermissionHandler) ListUserPermissions(ctx *gin.Context) {
+	var params model.ListUserPermissionsRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	permissions, err := h.permissionService.ListUserPermissions(params.UserId)
+	if err != nil {
+		InternalError(ctx, "查询失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", permissions)
+}
+
+// GrantUserPermission 给用户授予权限
+func (h *PermissionHandler) GrantUserPermission(ctx *gin.Context) {
+	var params model.UserPermissionRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.permissionService.GrantUserPermission(params.UserId, params.PermissionId); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "授权成功", nil)
+}
+
+// RevokeUserPermission 取消用户的权限
+func (h *PermissionHandler) RevokeUserPermission(ctx *gin.Context) {
+	var params model.UserPermissionRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.permissionService.RevokeUserPermission(params.UserId, params.PermissionId); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "取消授权成功", nil)
+}
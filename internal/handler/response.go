@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"users-by-go-example/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -46,6 +48,14 @@ func Unauthorized(ctx *gin.Context, message string) {
 	})
 }
 
+// Forbidden 403 禁止访问响应
+func Forbidden(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusForbidden, Response{
+		Code:    http.StatusForbidden,
+		Message: message,
+	})
+}
+
 // NotFound 404 未找到响应
 func NotFound(ctx *gin.Context, message string) {
 	ctx.JSON(http.StatusNotFound, Response{
@@ -54,6 +64,28 @@ func NotFound(ctx *gin.Context, message string) {
 	})
 }
 
+// BadRequestError 400 错误响应，密码不满足策略时在 data 中返回逐条违反的规则
+func BadRequestError(ctx *gin.Context, err error) {
+	var policyErr *utils.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		ctx.JSON(http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    gin.H{"violations": policyErr.Violations},
+		})
+		return
+	}
+	BadRequest(ctx, err.Error())
+}
+
+// TooManyRequests 429 请求过于频繁响应
+func TooManyRequests(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusTooManyRequests, Response{
+		Code:    http.StatusTooManyRequests,
+		Message: message,
+	})
+}
+
 // InternalError 500 服务器内部错误响应
 func InternalError(ctx *gin.Context, message string) {
 	ctx.JSON(http.StatusInternalServerError, Response{
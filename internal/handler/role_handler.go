@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleHandler 角色处理器
+type RoleHandler struct {
+	roleService *service.RoleService
+}
+
+// NewRoleHandler 创建角色处理器
+func NewRoleHandler() *RoleHandler {
+	return &RoleHandler{
+		roleService: &service.RoleService{},
+	}
+}
+
+// ListRoles 获取角色列表
+func (h *RoleHandler) ListRoles(ctx *gin.Context) {
+	roles, err := h.roleService.ListRoles()
+	if err != nil {
+		InternalError(ctx, "查询失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", roles)
+}
+
+// CreateRole 创建角色
+func (h *RoleHandler) CreateRole(ctx *gin.Context) {
+	var params model.CreateRoleRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	role, err := h.roleService.CreateRole(&params)
+	if err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "创建成功", role)
+}
+
+// DeleteRole 删除角色
+func (h *RoleHandler) DeleteRole(ctx *gin.Context) {
+	var params model.DeleteRoleRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.roleService.DeleteRole(params.ID); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "删除成功", nil)
+}
+
+// ListRolePermissions 获取角色拥有的权限
+func (h *RoleHandler) ListRolePermissions(ctx *gin.Context) {
+	var params model.ListRolePermissionsRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	permissions, err := h.roleService.ListRolePermissions(params.RoleId)
+	if err != nil {
+		InternalError(ctx, "查询失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", permissions)
+}
+
+// GrantPermission 给角色授予权限
+func (h *RoleHandler) GrantPermission(ctx *gin.Context) {
+	var params model.RolePermissionRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.roleService.GrantPermission(params.RoleId, params.PermissionId); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "授权成功", nil)
+}
+
+// RevokePermission 取消角色的权限
+func (h *RoleHandler) RevokePermission(ctx *gin.Context) {
+	var params model.RolePermissionRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.roleService.RevokePermission(params.RoleId, params.PermissionId); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "取消授权成功", nil)
+}
+
+// AssignRole 给用户分配角色
+func (h *RoleHandler) AssignRole(ctx *gin.Context) {
+	var params model.UserRoleRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.roleService.AssignRole(params.UserId, params.RoleId); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "分配成功", nil)
+}
+
+// RemoveRole 移除用户的角色
+func (h *RoleHandler) RemoveRole(ctx *gin.Context) {
+	var params model.UserRoleRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.roleService.RemoveRole(params.UserId, params.RoleId); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "移除成功", nil)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/service"
+	"users-by-go-example/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler 登录会话处理器
+type SessionHandler struct {
+	sessionService *service.SessionService
+}
+
+// NewSessionHandler 创建登录会话处理器
+func NewSessionHandler() *SessionHandler {
+	return &SessionHandler{
+		sessionService: &service.SessionService{},
+	}
+}
+
+// List 列出当前用户的登录会话
+func (h *SessionHandler) List(ctx *gin.Context) {
+	sessions, err := h.sessionService.List(ctx.GetInt64("userId"), currentSessionID(ctx))
+	if err != nil {
+		InternalError(ctx, "查询失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "查询成功", sessions)
+}
+
+// Revoke 吊销当前用户的某个会话（例如在其他设备上退出登录）
+func (h *SessionHandler) Revoke(ctx *gin.Context) {
+	var params model.RevokeSessionRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.sessionService.Revoke(ctx.GetInt64("userId"), params.ID); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			NotFound(ctx, err.Error())
+			return
+		}
+		InternalError(ctx, "吊销会话失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "吊销成功", nil)
+}
+
+// RevokeOthers 吊销除当前会话外的全部会话
+func (h *SessionHandler) RevokeOthers(ctx *gin.Context) {
+	count, err := h.sessionService.RevokeOthers(ctx.GetInt64("userId"), currentSessionID(ctx))
+	if err != nil {
+		InternalError(ctx, "吊销会话失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "吊销成功", gin.H{"count": count})
+}
+
+// currentSessionID 当前请求所属的会话
+func currentSessionID(ctx *gin.Context) string {
+	if claims, ok := ctx.Get("claims"); ok {
+		return claims.(*utils.Claims).SessionID
+	}
+	return ""
+}
@@ -1,22 +1,30 @@
 package handler
 
 import (
+	"errors"
 	"users-by-go-example/internal/model"
 	"users-by-go-example/internal/service"
 	"users-by-go-example/logger"
+	"users-by-go-example/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // UserHandler 用户处理器
 type UserHandler struct {
-	userService *service.UserService
+	userService       *service.UserService
+	tokenService      *service.TokenService
+	emailService      *service.EmailService
+	loginGuardService *service.LoginGuardService
 }
 
 // NewUserHandler 创建用户处理器
 func NewUserHandler() *UserHandler {
 	return &UserHandler{
-		userService: &service.UserService{},
+		userService:       &service.UserService{},
+		tokenService:      &service.TokenService{},
+		emailService:      &service.EmailService{},
+		loginGuardService: &service.LoginGuardService{},
 	}
 }
 
@@ -30,13 +38,29 @@ func (h *UserHandler) Register(ctx *gin.Context) {
 
 	user, err := h.userService.Register(&params)
 	if err != nil {
-		BadRequest(ctx, err.Error())
+		BadRequestError(ctx, err)
 		return
 	}
 
 	Success(ctx, "注册成功", user)
 }
 
+// VerifyEmail 通过邮件中的令牌完成邮箱验证
+func (h *UserHandler) VerifyEmail(ctx *gin.Context) {
+	var params model.VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.emailService.ConfirmVerification(params.Token); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "邮箱验证成功", nil)
+}
+
 // Login 用户登录
 func (h *UserHandler) Login(ctx *gin.Context) {
 	var params model.LoginRequest
@@ -47,15 +71,42 @@ func (h *UserHandler) Login(ctx *gin.Context) {
 
 	logger.GetLogger(ctx).Info("用户登录参数 %v", params)
 
-	token, err := h.userService.Login(&params)
+	tokens, err := h.userService.Login(&params, clientInfo(ctx))
 	if err != nil {
+		if errors.Is(err, service.ErrLoginLocked) {
+			TooManyRequests(ctx, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrAuthBackendUnavailable) {
+			logger.GetLogger(ctx).Error("登录校验失败: %v", err)
+			InternalError(ctx, service.ErrAuthBackendUnavailable.Error())
+			return
+		}
 		Unauthorized(ctx, err.Error())
 		return
 	}
 
-	Success(ctx, "登录成功", gin.H{
-		"token": token,
-	})
+	Success(ctx, "登录成功", tokens)
+}
+
+// RefreshToken 使用刷新令牌换取新的令牌对
+func (h *UserHandler) RefreshToken(ctx *gin.Context) {
+	var params model.RefreshTokenRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	tokens, err := h.tokenService.Refresh(params.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			logger.GetLogger(ctx).Warn("检测到刷新令牌重放，令牌家族已吊销 ip=%s", ctx.ClientIP())
+		}
+		Unauthorized(ctx, err.Error())
+		return
+	}
+
+	Success(ctx, "刷新成功", tokens)
 }
 
 // GetUserList 获取用户列表
@@ -101,6 +152,10 @@ func (h *UserHandler) GetUserByID(ctx *gin.Context) {
 		return
 	}
 
+	if !checkOwner(ctx, params.ID) {
+		return
+	}
+
 	user, err := h.userService.GetUserByID(params.ID)
 	if err != nil {
 		NotFound(ctx, err.Error())
@@ -118,9 +173,18 @@ func (h *UserHandler) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	if !checkOwner(ctx, params.ID) {
+		return
+	}
+	// 只凭 self_permits 修改自己时不能跳过原密码校验，直接设置密码只保留给拥有 user:update 的管理员
+	if params.Password != "" && ctx.GetBool("selfOnly") {
+		Forbidden(ctx, "修改自己的密码请使用 /api/v1/me/password")
+		return
+	}
+
 	user, err := h.userService.UpdateUser(params.ID, &params)
 	if err != nil {
-		BadRequest(ctx, err.Error())
+		BadRequestError(ctx, err)
 		return
 	}
 
@@ -135,6 +199,10 @@ func (h *UserHandler) DeleteUser(ctx *gin.Context) {
 		return
 	}
 
+	if !checkOwner(ctx, params.ID) {
+		return
+	}
+
 	if err := h.userService.DeleteUser(params.ID); err != nil {
 		BadRequest(ctx, err.Error())
 		return
@@ -142,3 +210,80 @@ func (h *UserHandler) DeleteUser(ctx *gin.Context) {
 
 	Success(ctx, "删除成功", nil)
 }
+
+// Logout 退出登录，吊销当前令牌
+func (h *UserHandler) Logout(ctx *gin.Context) {
+	var params model.LogoutRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&params); err != nil {
+			BadRequest(ctx, "参数错误: "+err.Error())
+			return
+		}
+	}
+
+	claims := ctx.MustGet("claims").(*utils.Claims)
+	if err := h.tokenService.Logout(claims, params.RefreshToken); err != nil {
+		InternalError(ctx, "退出登录失败: "+err.Error())
+		return
+	}
+
+	Success(ctx, "退出成功", nil)
+}
+
+// UnlockUser 解除用户的登录锁定
+func (h *UserHandler) UnlockUser(ctx *gin.Context) {
+	var params model.UnlockUserRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.loginGuardService.Unlock(params.ID); err != nil {
+		BadRequest(ctx, err.Error())
+		return
+	}
+
+	logger.GetLogger(ctx).Info("解除登录锁定 userId=%d operator=%v", params.ID, ctx.GetInt64("userId"))
+
+	Success(ctx, "解锁成功", nil)
+}
+
+// RevokeSessions 吊销指定用户的全部会话
+func (h *UserHandler) RevokeSessions(ctx *gin.Context) {
+	var params model.RevokeSessionsRequest
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		BadRequest(ctx, "参数错误: "+err.Error())
+		return
+	}
+
+	if _, err := h.userService.GetUserByID(params.ID); err != nil {
+		NotFound(ctx, err.Error())
+		return
+	}
+
+	if err := h.tokenService.RevokeAllSessions(params.ID); err != nil {
+		InternalError(ctx, "吊销会话失败: "+err.Error())
+		return
+	}
+
+	logger.GetLogger(ctx).Info("吊销用户全部会话 userId=%d operator=%v", params.ID, ctx.GetInt64("userId"))
+
+	Success(ctx, "吊销成功", nil)
+}
+
+// checkOwner 权限中间件只凭 self_permits 放行时，绑定后的目标用户必须是当前用户
+func checkOwner(ctx *gin.Context, id int64) bool {
+	if ctx.GetBool("selfOnly") && id != ctx.GetInt64("userId") {
+		Forbidden(ctx, "未授权的访问")
+		return false
+	}
+	return true
+}
+
+// clientInfo 当前请求的客户端信息，登录时记录到会话中
+func clientInfo(ctx *gin.Context) *model.ClientInfo {
+	return &model.ClientInfo{
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+}
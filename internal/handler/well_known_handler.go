@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WellKnownHandler 公开元数据处理器（/.well-known/*）
+type WellKnownHandler struct{}
+
+// NewWellKnownHandler 创建公开元数据处理器
+func NewWellKnownHandler() *WellKnownHandler {
+	return &WellKnownHandler{}
+}
+
+// JWKS 公开 JWT 验签公钥，按标准格式直接返回，不包装统一响应结构
+func (h *WellKnownHandler) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, utils.GetJWKS())
+}
+
+// OpenIDConfiguration 授权服务器元数据（OIDC Discovery），供第三方应用自动获取各接口地址
+func (h *WellKnownHandler) OpenIDConfiguration(ctx *gin.Context) {
+	conf := application.GetConfig().OAuth
+	issuer := strings.TrimSuffix(conf.Issuer, "/")
+
+	metadata := gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                conf.AuthorizeURL,
+		"token_endpoint":                        issuer + "/api/v1/oauth/token",
+		"introspection_endpoint":                issuer + "/api/v1/oauth/introspect",
+		"userinfo_endpoint":                     issuer + "/api/v1/oauth/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials"},
+		"subject_types_supported":               []string{"public"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+	}
+	if alg := utils.SigningAlg(); alg != "" {
+		metadata["id_token_signing_alg_values_supported"] = []string{alg}
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, metadata)
+}
@@ -0,0 +1,253 @@
+package ldapauth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"users-by-go-example/internal/config"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	ErrUserNotFound       = errors.New("LDAP 中不存在该用户")
+	ErrInvalidCredentials = errors.New("LDAP 用户名或密码错误")
+)
+
+// Conn 目录服务连接中用到的操作，*ldap.Conn 实现了该接口，测试中可替换为内存实现
+type Conn interface {
+	Bind(username, password string) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// DialFunc 建立目录服务连接
+type DialFunc func(conf *config.LDAPConfig) (Conn, error)
+
+// User 目录中的用户
+type User struct {
+	DN          string
+	Username    string
+	Email       string
+	DisplayName string
+	Groups      []string // 所属组的 DN
+}
+
+// Directory LDAP / Active Directory 用户目录：服务账号查找用户，再以用户 DN 绑定校验密码
+type Directory struct {
+	conf config.LDAPConfig
+	dial DialFunc
+}
+
+// NewDirectory 创建用户目录，dial 为 nil 时按配置连接真实的目录服务
+func NewDirectory(conf *config.LDAPConfig, dial DialFunc) (*Directory, error) {
+	if conf.URL == "" || conf.BaseDN == "" {
+		return nil, errors.New("LDAP 配置缺少 url 或 base-dn")
+	}
+	if !strings.Contains(conf.UserFilter, "%s") {
+		return nil, errors.New("LDAP user-filter 必须包含 %s")
+	}
+
+	d := &Directory{conf: *conf, dial: dial}
+	if d.dial == nil {
+		d.dial = Dial
+	}
+	if d.conf.UsernameAttribute == "" {
+		d.conf.UsernameAttribute = "uid"
+	}
+	if d.conf.EmailAttribute == "" {
+		d.conf.EmailAttribute = "mail"
+	}
+	if d.conf.DisplayNameAttribute == "" {
+		d.conf.DisplayNameAttribute = "cn"
+	}
+	if d.conf.GroupBaseDN == "" {
+		d.conf.GroupBaseDN = d.conf.BaseDN
+	}
+	return d, nil
+}
+
+// Config 目录配置
+func (d *Directory) Config() *config.LDAPConfig {
+	return &d.conf
+}
+
+// Dial 连接目录服务，按配置启用 StartTLS
+func Dial(conf *config.LDAPConfig) (Conn, error) {
+	timeout := time.Duration(conf.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP url 格式错误: %w", err)
+	}
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: conf.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(conf.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+
+	if conf.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// Authenticate 校验登录名和密码，成功时返回目录中的用户及其所属组
+func (d *Directory) Authenticate(username, password string) (*User, error) {
+	// 空密码会被目录服务当作匿名绑定而返回成功，必须提前拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := d.dial(&d.conf)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := d.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	entry, err := d.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	user := &User{
+		DN:          entry.DN,
+		Username:    entry.GetAttributeValue(d.conf.UsernameAttribute),
+		Email:       entry.GetAttributeValue(d.conf.EmailAttribute),
+		DisplayName: entry.GetAttributeValue(d.conf.DisplayNameAttribute),
+		Groups:      entry.GetAttributeValues("memberOf"),
+	}
+	if user.Username == "" {
+		user.Username = username
+	}
+
+	if d.conf.GroupFilter != "" {
+		// 用户绑定后可能没有查询组的权限，切回服务账号
+		if err := d.bindService(conn); err != nil {
+			return nil, err
+		}
+		groups, err := d.findGroups(conn, entry.DN)
+		if err != nil {
+			return nil, err
+		}
+		user.Groups = mergeGroups(user.Groups, groups)
+	}
+	return user, nil
+}
+
+// InGroup 用户是否属于指定组，group 可以是组的 DN 或 CN，不区分大小写
+func (u *User) InGroup(group string) bool {
+	for _, dn := range u.Groups {
+		if strings.EqualFold(dn, group) || strings.EqualFold(commonName(dn), group) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Directory) bindService(conn Conn) error {
+	if d.conf.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(d.conf.BindDN, d.conf.BindPassword); err != nil {
+		return fmt.Errorf("LDAP 服务账号绑定失败: %w", err)
+	}
+	return nil
+}
+
+func (d *Directory) findUser(conn Conn, username string) (*ldap.Entry, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		d.conf.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		strings.ReplaceAll(d.conf.UserFilter, "%s", ldap.EscapeFilter(username)),
+		[]string{d.conf.UsernameAttribute, d.conf.EmailAttribute, d.conf.DisplayNameAttribute, "memberOf"},
+		nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, err
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+	// 登录名匹配到多个用户时无法确定身份，按校验失败处理
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("LDAP 登录名 %s 匹配到多个用户", username)
+	}
+	return result.Entries[0], nil
+}
+
+func (d *Directory) findGroups(conn Conn, userDN string) ([]string, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		d.conf.GroupBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		strings.ReplaceAll(d.conf.GroupFilter, "%s", ldap.EscapeFilter(userDN)),
+		[]string{"cn"},
+		nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.DN)
+	}
+	return groups, nil
+}
+
+// mergeGroups 合并 memberOf 和组查询结果，按 DN 去重
+func mergeGroups(a, b []string) []string {
+	merged := append([]string{}, a...)
+	for _, dn := range b {
+		exists := false
+		for _, existing := range merged {
+			if strings.EqualFold(existing, dn) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			merged = append(merged, dn)
+		}
+	}
+	return merged
+}
+
+// commonName 返回 DN 第一个 RDN 的 CN 值，不是 CN 时返回空字符串
+func commonName(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 {
+		return ""
+	}
+	for _, attr := range parsed.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, "cn") {
+			return attr.Value
+		}
+	}
+	return ""
+}
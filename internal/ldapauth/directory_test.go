@@ -0,0 +1,181 @@
+package ldapauth
+
+import (
+	"strings"
+	"testing"
+	"users-by-go-example/internal/config"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDirectory 进程内模拟的目录服务，按真实的过滤器语法匹配条目
+type fakeDirectory struct {
+	entries   []*ldap.Entry
+	passwords map[string]string // DN -> 密码
+	filters   []string          // 收到的查询过滤器
+}
+
+type fakeConn struct {
+	dir   *fakeDirectory
+	bound string
+}
+
+func (c *fakeConn) Bind(dn, password string) error {
+	if expected, ok := c.dir.passwords[dn]; !ok || expected != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, nil)
+	}
+	c.bound = dn
+	return nil
+}
+
+func (c *fakeConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if c.bound != "cn=svc,dc=example,dc=com" {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, nil)
+	}
+	c.dir.filters = append(c.dir.filters, req.Filter)
+
+	filter, err := ldap.CompileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	result := &ldap.SearchResult{}
+	for _, entry := range c.dir.entries {
+		if strings.HasSuffix(strings.ToLower(entry.DN), strings.ToLower(req.BaseDN)) && matchFilter(filter, entry) {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	if req.SizeLimit > 0 && len(result.Entries) > req.SizeLimit {
+		result.Entries = result.Entries[:req.SizeLimit]
+		return result, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, nil)
+	}
+	return result, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func matchFilter(f *ber.Packet, entry *ldap.Entry) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, child := range f.Children {
+			if !matchFilter(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range f.Children {
+			if matchFilter(child, entry) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchFilter(f.Children[0], entry)
+	case ldap.FilterPresent:
+		return len(entry.GetAttributeValues(ber.DecodeString(f.Data.Bytes()))) > 0
+	case ldap.FilterEqualityMatch:
+		attr := ber.DecodeString(f.Children[0].Data.Bytes())
+		value := ber.DecodeString(f.Children[1].Data.Bytes())
+		for _, v := range entry.GetAttributeValues(attr) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newTestDirectory(t *testing.T, groupFilter string) (*Directory, *fakeDirectory) {
+	fake := &fakeDirectory{
+		entries: []*ldap.Entry{
+			ldap.NewEntry("uid=alice,ou=people,dc=example,dc=com", map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"alice"},
+				"mail":        {"alice@example.com"},
+				"cn":          {"Alice Liddell"},
+				"memberOf":    {"cn=staff,ou=groups,dc=example,dc=com"},
+			}),
+			ldap.NewEntry("uid=bob,ou=people,dc=example,dc=com", map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"bob"},
+			}),
+			ldap.NewEntry("cn=admins,ou=groups,dc=example,dc=com", map[string][]string{
+				"objectClass": {"groupOfNames"},
+				"cn":          {"admins"},
+				"member":      {"uid=alice,ou=people,dc=example,dc=com"},
+			}),
+		},
+		passwords: map[string]string{
+			"cn=svc,dc=example,dc=com":              "svc-secret",
+			"uid=alice,ou=people,dc=example,dc=com": "alice-pw",
+			"uid=bob,ou=people,dc=example,dc=com":   "bob-pw",
+		},
+	}
+
+	dir, err := NewDirectory(&config.LDAPConfig{
+		URL:          "ldap://ldap.example.com",
+		BindDN:       "cn=svc,dc=example,dc=com",
+		BindPassword: "svc-secret",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		UserFilter:   "(&(objectClass=person)(|(uid=%s)(mail=%s)))",
+		GroupBaseDN:  "ou=groups,dc=example,dc=com",
+		GroupFilter:  groupFilter,
+	}, func(*config.LDAPConfig) (Conn, error) {
+		return &fakeConn{dir: fake}, nil
+	})
+	require.NoError(t, err)
+	return dir, fake
+}
+
+func TestDirectory_Authenticate(t *testing.T) {
+	dir, _ := newTestDirectory(t, "(&(objectClass=groupOfNames)(member=%s))")
+
+	user, err := dir.Authenticate("alice", "alice-pw")
+	require.NoError(t, err)
+	assert.Equal(t, "uid=alice,ou=people,dc=example,dc=com", user.DN)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "alice@example.com", user.Email)
+	assert.Equal(t, "Alice Liddell", user.DisplayName)
+
+	// memberOf 和组查询的结果合并
+	assert.Len(t, user.Groups, 2)
+	assert.True(t, user.InGroup("staff"))
+	assert.True(t, user.InGroup("CN=Admins,OU=Groups,DC=example,DC=com"))
+	assert.False(t, user.InGroup("ops"))
+
+	// 使用邮箱登录
+	user, err = dir.Authenticate("alice@example.com", "alice-pw")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+}
+
+func TestDirectory_AuthenticateRejects(t *testing.T) {
+	dir, fake := newTestDirectory(t, "")
+
+	_, err := dir.Authenticate("alice", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// 空密码不能当作匿名绑定通过
+	_, err = dir.Authenticate("alice", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = dir.Authenticate("carol", "pw")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	// 登录名中的过滤器特殊字符被转义，不能注入通配
+	_, err = dir.Authenticate("*", "bob-pw")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.Contains(t, fake.filters[len(fake.filters)-1], `uid=\2a`)
+
+	user, err := dir.Authenticate("bob", "bob-pw")
+	require.NoError(t, err)
+	assert.Empty(t, user.Groups)
+}
+
+func TestNewDirectory_InvalidConfig(t *testing.T) {
+	_, err := NewDirectory(&config.LDAPConfig{URL: "ldap://x", BaseDN: "dc=x", UserFilter: "(uid=alice)"}, nil)
+	assert.Error(t, err)
+}
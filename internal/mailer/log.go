@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LogMailer 把邮件写入文件或标准错误，用于本地开发和测试，无需邮件服务器
+type LogMailer struct {
+	from string
+	mu   sync.Mutex
+	out  io.Writer
+}
+
+// NewLogMailer 创建日志邮件发送器，file 为空时输出到标准错误
+func NewLogMailer(from, file string) (*LogMailer, error) {
+	m := &LogMailer{from: from, out: os.Stderr}
+	if file != "" {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		m.out = f
+	}
+	return m, nil
+}
+
+// Send 记录邮件内容
+func (m *LogMailer) Send(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.out, "==== %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.DateTime), m.from, msg.To, msg.Subject, msg.Body)
+	return err
+}
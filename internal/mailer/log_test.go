@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailer_Send(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mail", "mail.log")
+
+	m, err := NewLogMailer("no-reply@example.com", file)
+	assert.NoError(t, err)
+
+	err = m.Send(&Message{
+		To:      "user@example.com",
+		Subject: "重置密码",
+		Body:    "reset link: http://localhost/reset?token=abc",
+	})
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "To: user@example.com")
+	assert.Contains(t, string(data), "Subject: 重置密码")
+	assert.Contains(t, string(data), "token=abc")
+}
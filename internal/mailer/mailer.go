@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"fmt"
+	"users-by-go-example/internal/config"
+)
+
+// Message 邮件内容
+type Message struct {
+	To      string
+	Subject string
+	Body    string // 纯文本正文
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// New 按配置创建邮件发送器
+func New(conf *config.MailConfig) (Mailer, error) {
+	switch conf.Driver {
+	case "smtp":
+		return NewSMTPMailer(conf), nil
+	case "", "log":
+		return NewLogMailer(conf.From, conf.LogFile)
+	default:
+		return nil, fmt.Errorf("不支持的邮件驱动: %s", conf.Driver)
+	}
+}
@@ -1,16 +1,22 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"users-by-go-example/internal/service"
 	"users-by-go-example/logger"
 	"users-by-go-example/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthorizationCheck JWT 认证中间件
+// AuthorizationCheck 认证中间件：支持 Authorization: Bearer <JWT> 或 X-API-Key: <API Key>
 func AuthorizationCheck() gin.HandlerFunc {
+	tokenService := &service.TokenService{}
+	apiKeyService := &service.ApiKeyService{}
+	sessionService := &service.SessionService{}
+
 	return func(ctx *gin.Context) {
 		// 检查是否在白名单中
 		//cfg := global.GetConfig()
@@ -22,6 +28,31 @@ func AuthorizationCheck() gin.HandlerFunc {
 		//	}
 		//}
 
+		// 机器间调用使用 API Key，以密钥所属用户的身份执行，权限受密钥 scopes 限制
+		if rawKey := ctx.GetHeader("X-API-Key"); rawKey != "" {
+			apiKey, user, err := apiKeyService.Authenticate(rawKey)
+			if err != nil {
+				if !errors.Is(err, service.ErrApiKeyInvalid) {
+					logger.GetLogger(ctx).Error("校验 API Key 失败: %v", err)
+				}
+				ctx.JSON(http.StatusUnauthorized, gin.H{
+					"code":    http.StatusUnauthorized,
+					"message": "API Key 无效、已过期或已吊销",
+				})
+				ctx.Abort()
+				return
+			}
+
+			logger.GetLogger(ctx).Info("ApiKey id=%d prefix=%s userId=%d", apiKey.ID, apiKey.Prefix, user.ID)
+
+			ctx.Set("userId", user.ID)
+			ctx.Set("username", user.Username)
+			ctx.Set("apiKey", apiKey)
+
+			ctx.Next()
+			return
+		}
+
 		// 获取 Authorization header
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
@@ -55,11 +86,37 @@ func AuthorizationCheck() gin.HandlerFunc {
 			return
 		}
 
+		// 检查 token 是否已被吊销
+		if err := tokenService.CheckAccessToken(claims); err != nil {
+			if !errors.Is(err, service.ErrTokenRevoked) {
+				logger.GetLogger(ctx).Error("检查令牌吊销状态失败: %v", err)
+			}
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"code":    http.StatusUnauthorized,
+				"message": "认证令牌无效或已过期",
+			})
+			ctx.Abort()
+			return
+		}
+
+		if claims.SessionID != "" {
+			sessionService.Touch(claims.SessionID, ctx.ClientIP())
+		}
+
 		logger.GetLogger(ctx).Info("LoginUser=%+v", claims)
 
+		// 模拟登录的每个请求都记录真实操作人，可按 jti 关联审计记录
+		if claims.Actor != nil {
+			logger.GetLogger(ctx).Warn("Impersonation actor=%d(%s) userId=%d(%s) jti=%s %s %s",
+				claims.Actor.UserID, claims.Actor.Username, claims.UserID, claims.Username, claims.ID,
+				ctx.Request.Method, ctx.Request.URL.Path)
+			ctx.Set("actorId", claims.Actor.UserID)
+		}
+
 		// 将用户信息存储到上下文中
 		ctx.Set("userId", claims.UserID)
 		ctx.Set("username", claims.Username)
+		ctx.Set("claims", claims)
 
 		ctx.Next()
 	}
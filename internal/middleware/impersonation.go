@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/logger"
+	"users-by-go-example/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonationGuard 模拟登录期间只允许访问 impersonation.allowed-paths 中的接口，其余一律拒绝
+// 按路由模板精确匹配，新增的接口默认不能在模拟期间调用
+func ImpersonationGuard() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, exists := ctx.Get("claims")
+		if !exists {
+			ctx.Next()
+			return
+		}
+		claims := value.(*utils.Claims)
+		if claims.Actor == nil {
+			ctx.Next()
+			return
+		}
+
+		route := ctx.FullPath()
+		if route == "" || !slices.Contains(application.GetConfig().Impersonation.AllowedPaths, route) {
+			logger.GetLogger(ctx).Warn("模拟登录期间禁止访问 %s actor=%d userId=%d", ctx.Request.URL.Path, claims.Actor.UserID, claims.UserID)
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"code":    http.StatusForbidden,
+				"message": "模拟登录期间不能执行该操作",
+			})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"users-by-go-example/internal/handler"
+	"users-by-go-example/internal/testenv"
+	"users-by-go-example/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newImpersonationRouter 以 X-Test-Actor 请求头模拟携带 act 声明的模拟令牌，只测试模拟登录拦截
+func newImpersonationRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	ok := func(ctx *gin.Context) { handler.Success(ctx, "ok", nil) }
+
+	router := gin.New()
+	v1 := router.Group("/api/v1")
+	v1.Use(func(ctx *gin.Context) {
+		claims := &utils.Claims{UserID: 2, Username: "alice"}
+		if ctx.GetHeader("X-Test-Actor") != "" {
+			claims.Actor = &utils.Actor{UserID: 1, Username: "admin"}
+		}
+		ctx.Set("claims", claims)
+	}, ImpersonationGuard())
+
+	for _, route := range []string{
+		"/logout", "/me/get", "/users/get",
+		"/users/update", "/users/delete", "/users/revoke-sessions",
+		"/me/password", "/me/totp/disable", "/impersonation/start",
+	} {
+		v1.POST(route, ok)
+	}
+	return router
+}
+
+func doImpersonationRequest(router *gin.Engine, path string, impersonating bool) int {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	if impersonating {
+		req.Header.Set("X-Test-Actor", "1")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestImpersonationGuard(t *testing.T) {
+	testenv.Setup(t, testenv.Config(t))
+	router := newImpersonationRouter()
+
+	// 模拟期间只能调用 allowed-paths 中的接口
+	for _, path := range []string{"/api/v1/logout", "/api/v1/me/get", "/api/v1/users/get"} {
+		assert.Equal(t, http.StatusOK, doImpersonationRequest(router, path, true), path)
+	}
+
+	// 修改凭据、账号状态的接口，以及配置中未列出的接口一律拒绝
+	for _, path := range []string{
+		"/api/v1/users/update",
+		"/api/v1/users/delete",
+		"/api/v1/users/revoke-sessions",
+		"/api/v1/me/password",
+		"/api/v1/me/totp/disable",
+		"/api/v1/impersonation/start",
+	} {
+		assert.Equal(t, http.StatusForbidden, doImpersonationRequest(router, path, true), path)
+	}
+
+	// 普通令牌不受影响
+	for _, path := range []string{"/api/v1/users/update", "/api/v1/users/delete", "/api/v1/me/password"} {
+		assert.Equal(t, http.StatusOK, doImpersonationRequest(router, path, false), path)
+	}
+}
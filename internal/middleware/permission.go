@@ -1,31 +1,45 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"net/http"
 	"strings"
 	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/permit"
+	"users-by-go-example/internal/service"
 	"users-by-go-example/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+// superUserExpr 未配置权限的接口所需的权限
+var superUserExpr, _ = permit.Parse("*")
+
 func PermissionCheck() gin.HandlerFunc {
+	permissionService := &service.PermissionService{}
+
 	return func(ctx *gin.Context) {
 		method := ctx.Request.Method
 		path := ctx.Request.URL.Path
 
-		key := method + " " + path
-		permitValue := application.GetApiPermitsMap()[key]
-
-		var permits []string
-		if strings.Contains(permitValue, ",") {
-			permits = strings.Split(permitValue, ",")
-		} else {
-			permits = append(permits, permitValue)
-		}
+		key := method + " " + ctx.FullPath()
+		apiPermit := application.GetApiPermits(method, ctx.FullPath(), path)
 
 		log := logger.GetLogger(ctx)
-		log.Info("Api=%s Permits=%s", key, permits)
+
+		// 默认拒绝模式下，未配置权限的接口一律禁止访问
+		if apiPermit == nil && application.GetConfig().Permission.DefaultDeny {
+			log.Warn("Api=%s 未配置权限，默认拒绝", key)
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"code":    http.StatusForbidden,
+				"message": "未授权的访问",
+			})
+			ctx.Abort()
+			return
+		}
 
 		userId, exists := ctx.Get("userId")
 		if !exists {
@@ -37,38 +51,129 @@ func PermissionCheck() gin.HandlerFunc {
 			return
 		}
 
-		permitsOfUser := make([]string, 10)
-		application.GetDB().Raw(`
-						SELECT permit
-						FROM permission p
-						JOIN user_permission up ON p.id = up.permission_id
-						WHERE up.user_id = ?`, userId).Scan(&permitsOfUser)
-
-		log.Info("user permits=%v\n", permitsOfUser)
+		var apiKey *model.ApiKey
+		if v, ok := ctx.Get("apiKey"); ok {
+			apiKey = v.(*model.ApiKey)
+		}
 
-		permitOfUserMap := make(map[string]bool)
-		for _, permit := range permitsOfUser {
-			if permit != "" {
-				permitOfUserMap[permit] = true
+		// 未配置权限的接口与原有行为一致，只有拥有 * 的用户可以访问；只要求登录的接口必须在 api_permits 中显式配置
+		expr := superUserExpr
+		var selfExpr permit.Expr
+		if apiPermit != nil {
+			expr = apiPermit.Permits
+			// 操作自己的资源时满足 self_permits 即可，操作他人的资源仍需管理权限
+			if apiPermit.OwnerField != "" {
+				if ownerId, ok := ownerIdFromBody(ctx, apiPermit.OwnerField); ok && ownerId == userId.(int64) {
+					if apiPermit.SelfPermits == nil && apiKey == nil {
+						ctx.Set("selfOnly", true)
+						ctx.Next()
+						return
+					}
+					selfExpr = apiPermit.SelfPermits
+				}
 			}
 		}
 
-		if permitOfUserMap["*"] {
+		log.Info("Api=%s Permits=%v SelfPermits=%v", key, expr, selfExpr)
+
+		// API Key 只能访问配置了权限标识的接口，不能访问修改密码、退出登录等只要求登录的接口
+		if expr == nil && apiKey != nil {
+			log.Warn("Api=%s 未配置权限标识，拒绝 API Key 访问", key)
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"code":    http.StatusForbidden,
+				"message": "API Key 不能访问该接口",
+			})
+			ctx.Abort()
+			return
+		}
+
+		// 显式配置为空权限标识的接口只要求登录
+		if expr == nil {
 			ctx.Next()
 			return
 		}
 
-		for _, permit := range permits {
-			if !permitOfUserMap[permit] {
-				ctx.JSON(http.StatusForbidden, gin.H{
-					"code":    http.StatusForbidden,
-					"message": "未授权的访问",
-				})
-				ctx.Abort()
-				return
+		// 有效权限 = 直接授予的权限 ∪ 角色授予的权限
+		permitsOfUser, hit, err := permissionService.GetCachedUserPermits(userId.(int64))
+		if err != nil {
+			log.Error("查询用户权限失败: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    http.StatusInternalServerError,
+				"message": "查询用户权限失败",
+			})
+			ctx.Abort()
+			return
+		}
+
+		hits, misses := service.PermitsCacheStats()
+		log.Info("user permits=%v cacheHit=%t hits=%d misses=%d", permitsOfUser, hit, hits, misses)
+
+		// 单个权限标识支持分段通配（user:*、*:list）和层级权限（user 包含 user:get）
+		// 使用 API Key 时还必须在密钥的 scopes 范围内，用户失去的权限密钥也随之失去
+		has := func(required string) bool {
+			if apiKey != nil && !permit.MatchAny(apiKey.ScopeList(), required) {
+				return false
 			}
+			return permit.MatchAny(permitsOfUser, required)
+		}
+		allowed := expr.Eval(has)
+		if !allowed && selfExpr != nil && selfExpr.Eval(has) {
+			// 只凭 self_permits 放行，handler 绑定参数后还要确认操作的确实是自己的资源
+			allowed = true
+			ctx.Set("selfOnly", true)
+		}
+		if !allowed {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"code":    http.StatusForbidden,
+				"message": "未授权的访问",
+			})
+			ctx.Abort()
+			return
 		}
 
 		ctx.Next()
 	}
 }
+
+// ownerIdFromBody 从 JSON 请求体中读取目标用户 ID，读取后恢复请求体供后续 handler 绑定
+// handler 绑定时字段名不区分大小写，出现重复或仅大小写不同的同名字段时无法确定实际绑定的值，视为无法识别
+func ownerIdFromBody(ctx *gin.Context, field string) (int64, bool) {
+	if ctx.Request.Body == nil {
+		return 0, false
+	}
+
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return 0, false
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return 0, false
+	}
+
+	var raw json.RawMessage
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return 0, false
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return 0, false
+		}
+		if key, _ := token.(string); strings.EqualFold(key, field) {
+			if raw != nil {
+				return 0, false
+			}
+			raw = value
+		}
+	}
+
+	var ownerId int64
+	if err := json.Unmarshal(raw, &ownerId); err != nil {
+		return 0, false
+	}
+	return ownerId, true
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"users-by-go-example/internal/handler"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPermissionRouter 以 X-Test-User 请求头模拟已认证的用户，只测试权限中间件和 handler
+func newPermissionRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	userHandler := handler.NewUserHandler()
+
+	router := gin.New()
+	v1 := router.Group("/api/v1")
+	v1.Use(func(ctx *gin.Context) {
+		userId, _ := strconv.ParseInt(ctx.GetHeader("X-Test-User"), 10, 64)
+		ctx.Set("userId", userId)
+	}, PermissionCheck())
+
+	v1.POST("/users/get", userHandler.GetUserByID)
+	v1.POST("/users/update", userHandler.UpdateUser)
+	v1.POST("/users/delete", userHandler.DeleteUser)
+	v1.POST("/logout", func(ctx *gin.Context) { handler.Success(ctx, "ok", nil) })
+	v1.POST("/unlisted", func(ctx *gin.Context) { handler.Success(ctx, "ok", nil) })
+	return router
+}
+
+func doRequest(router *gin.Engine, user *model.User, path, body string) int {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", strconv.FormatInt(user.ID, 10))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestPermissionCheck_Owner(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	router := newPermissionRouter()
+
+	self := env.CreateUser(t, "alice", "self:update", "self:delete", "self:get")
+	victim := env.CreateUser(t, "bob")
+	admin := env.CreateUser(t, "admin", "user:update")
+
+	// 操作自己的账号只需 self_permits
+	assert.Equal(t, http.StatusOK, doRequest(router, self, "/api/v1/users/update", `{"id":1,"nikeName":"a"}`))
+
+	// 大小写不同的重复字段：中间件无法确定 handler 绑定的是哪一个，不能按本人处理
+	for _, body := range []string{
+		`{"id":1,"ID":2,"password":"Passw0rd!x"}`,
+		`{"ID":2,"id":1,"password":"Passw0rd!x"}`,
+		`{"id":1,"id":2,"password":"Passw0rd!x"}`,
+		`{"Id":2}`,
+	} {
+		assert.Equal(t, http.StatusForbidden, doRequest(router, self, "/api/v1/users/update", body), body)
+		assert.Equal(t, http.StatusForbidden, doRequest(router, self, "/api/v1/users/delete", body), body)
+		assert.Equal(t, http.StatusForbidden, doRequest(router, self, "/api/v1/users/get", body), body)
+	}
+
+	var stored model.User
+	require.NoError(t, env.DB.First(&stored, victim.ID).Error)
+	assert.Equal(t, "-", stored.Password)
+	assert.Equal(t, 0, stored.Delete)
+
+	// 管理员仍可按 permits 操作他人
+	assert.Equal(t, http.StatusOK, doRequest(router, admin, "/api/v1/users/update", `{"id":2,"nikeName":"b"}`))
+	assert.Equal(t, http.StatusForbidden, doRequest(router, admin, "/api/v1/users/delete", `{"id":2}`))
+}
+
+func TestPermissionCheck_Unlisted(t *testing.T) {
+	conf := testenv.Config(t)
+	conf.Permission.DefaultDeny = false
+	env := testenv.Setup(t, conf)
+	router := newPermissionRouter()
+
+	user := env.CreateUser(t, "alice")
+	root := env.CreateUser(t, "root", "*")
+
+	// 显式配置为空权限的接口只要求登录
+	assert.Equal(t, http.StatusOK, doRequest(router, user, "/api/v1/logout", ``))
+
+	// 未配置的接口只有拥有 * 的用户可以访问
+	assert.Equal(t, http.StatusForbidden, doRequest(router, user, "/api/v1/unlisted", ``))
+	assert.Equal(t, http.StatusOK, doRequest(router, root, "/api/v1/unlisted", ``))
+
+	// 默认拒绝模式下一律禁止
+	conf.Permission.DefaultDeny = true
+	assert.Equal(t, http.StatusForbidden, doRequest(router, root, "/api/v1/unlisted", ``))
+}
+
+func TestUpdateUser_SelfPassword(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	router := newPermissionRouter()
+
+	self := env.CreateUser(t, "alice", "self:update")
+	admin := env.CreateUser(t, "admin", "user:update")
+	body := func(user *model.User, extra string) string {
+		return `{"id":` + strconv.FormatInt(user.ID, 10) + extra + `}`
+	}
+
+	// 只凭 self_permits 不能绕过原密码校验直接设置密码，修改昵称不受影响
+	assert.Equal(t, http.StatusForbidden, doRequest(router, self, "/api/v1/users/update", body(self, `,"password":"N3w-Passw0rd!x"`)))
+	assert.Equal(t, http.StatusOK, doRequest(router, self, "/api/v1/users/update", body(self, `,"nikeName":"a"`)))
+
+	var stored model.User
+	require.NoError(t, env.DB.First(&stored, self.ID).Error)
+	assert.Equal(t, "-", stored.Password)
+
+	// 管理员仍可重置他人的密码
+	assert.Equal(t, http.StatusOK, doRequest(router, admin, "/api/v1/users/update", body(self, `,"password":"N3w-Passw0rd!x"`)))
+	require.NoError(t, env.DB.First(&stored, self.ID).Error)
+	assert.NotEqual(t, "-", stored.Password)
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	ApiKeyTypePersonal = "personal" // 用户为自己创建的个人密钥
+	ApiKeyTypeService  = "service"  // 管理员为服务账号创建的服务密钥
+)
+
+// ApiKey API 密钥，只保存摘要，明文只在创建时返回一次
+type ApiKey struct {
+	ID           int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId       int64      `gorm:"column:user_id" json:"userId"` // 所属用户，请求以该用户的身份执行
+	Name         string     `gorm:"column:name" json:"name"`
+	Type         string     `gorm:"column:type" json:"type"`
+	Prefix       string     `gorm:"column:prefix" json:"prefix"` // 密钥前几位，便于识别
+	KeyHash      string     `gorm:"column:key_hash" json:"-"`
+	Scopes       string     `gorm:"column:scopes" json:"-"` // 逗号分隔的权限标识，必须是所属用户权限的子集
+	ExpireTime   *time.Time `gorm:"column:expire_time" json:"expireTime"`
+	LastUsedTime *time.Time `gorm:"column:last_used_time" json:"lastUsedTime"`
+	Revoked      bool       `gorm:"column:revoked" json:"revoked"`
+	CreateTime   time.Time  `gorm:"column:create_time;autoCreateTime" json:"createTime"`
+}
+
+func (*ApiKey) TableName() string {
+	return "api_key"
+}
+
+// ScopeList 密钥授权的权限标识
+func (k *ApiKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// ApiKeyResponse API 密钥响应
+type ApiKeyResponse struct {
+	*ApiKey
+	ScopeList []string `json:"scopes"`
+	Key       string   `json:"key,omitempty"` // 明文密钥，只在创建时返回
+}
+
+func (k *ApiKey) ToResponse() *ApiKeyResponse {
+	return &ApiKeyResponse{ApiKey: k, ScopeList: k.ScopeList()}
+}
+
+// CreateApiKeyRequest 创建 API 密钥请求
+type CreateApiKeyRequest struct {
+	UserId     int64    `json:"userId"` // 仅管理员创建服务密钥时使用
+	Name       string   `json:"name" binding:"required,max=50"`
+	Scopes     []string `json:"scopes" binding:"required,min=1,dive,required,max=100"`
+	ExpireDays int      `json:"expireDays" binding:"min=0,max=3650"` // 0 表示永不过期
+}
+
+// ListApiKeysRequest 查询 API 密钥请求
+type ListApiKeysRequest struct {
+	UserId int64 `json:"userId"`
+}
+
+// RevokeApiKeyRequest 吊销 API 密钥请求
+type RevokeApiKeyRequest struct {
+	ID int64 `json:"id" binding:"required"`
+}
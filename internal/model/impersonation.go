@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// ImpersonationLog 模拟登录审计记录，每次签发模拟令牌记录一条
+type ImpersonationLog struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ActorId    int64     `gorm:"column:actor_id" json:"actorId"` // 真实操作人
+	UserId     int64     `gorm:"column:user_id" json:"userId"`   // 被模拟的用户
+	Reason     string    `gorm:"column:reason" json:"reason"`
+	TokenId    string    `gorm:"column:token_id" json:"tokenId"` // 模拟令牌的 jti，请求日志中按 jti 关联
+	IP         string    `gorm:"column:ip" json:"ip"`
+	ExpireTime time.Time `gorm:"column:expire_time" json:"expireTime"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime" json:"createTime"`
+}
+
+func (*ImpersonationLog) TableName() string {
+	return "impersonation_log"
+}
+
+// ImpersonateRequest 模拟登录请求
+type ImpersonateRequest struct {
+	UserId int64  `json:"userId" binding:"required"`
+	Reason string `json:"reason" binding:"required,max=200"` // 例如工单号，写入审计记录
+}
+
+// ImpersonateResponse 模拟登录响应，令牌不能刷新，到期后需重新申请
+type ImpersonateResponse struct {
+	Token     string        `json:"token"`
+	ExpiresIn int64         `json:"expiresIn"` // 秒
+	User      *UserResponse `json:"user"`
+}
+
+// ListImpersonationLogsRequest 查询模拟登录审计记录请求
+type ListImpersonationLogsRequest struct {
+	ActorId  int64 `json:"actorId"`
+	UserId   int64 `json:"userId"`
+	Page     int   `json:"page" binding:"omitempty,min=1"`
+	PageSize int   `json:"pageSize" binding:"omitempty,min=1,max=100"`
+}
@@ -0,0 +1,37 @@
+package model
+
+// LoginResponse 登录响应：开启两步验证时只返回 mfaToken，需调用 /login/mfa 换取令牌
+type LoginResponse struct {
+	*TokenResponse
+	MfaRequired bool   `json:"mfaRequired,omitempty"`
+	MfaToken    string `json:"mfaToken,omitempty"`
+}
+
+// MfaLoginRequest 两步验证登录请求，验证码和恢复码二选一
+type MfaLoginRequest struct {
+	MfaToken     string `json:"mfaToken" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// TotpEnrollResponse 绑定 TOTP 响应
+type TotpEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth:// 链接，前端转成二维码
+}
+
+// TotpConfirmRequest 确认绑定 TOTP 请求
+type TotpConfirmRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TotpDisableRequest 关闭 TOTP 请求，验证码和恢复码二选一
+type TotpDisableRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// RecoveryCodesResponse 恢复码响应，明文只返回这一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OAuthClient 接入本服务登录的第三方应用
+type OAuthClient struct {
+	ID           int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ClientId     string    `gorm:"column:client_id" json:"clientId"`
+	SecretHash   string    `gorm:"column:secret_hash" json:"-"` // 公开客户端（SPA、移动端）为空
+	Name         string    `gorm:"column:name" json:"name"`
+	RedirectURIs string    `gorm:"column:redirect_uris" json:"-"` // 逗号分隔，回调地址必须完全匹配其一
+	GrantTypes   string    `gorm:"column:grant_types" json:"-"`   // 逗号分隔
+	Scopes       string    `gorm:"column:scopes" json:"-"`        // 逗号分隔，允许申请的 scope，权限标识支持通配
+	CreateTime   time.Time `gorm:"column:create_time;autoCreateTime" json:"createTime"`
+}
+
+func (*OAuthClient) TableName() string {
+	return "oauth_client"
+}
+
+// Public 是否为公开客户端（无法保存密钥，只能使用授权码 + PKCE）
+func (c *OAuthClient) Public() bool {
+	return c.SecretHash == ""
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+func (c *OAuthClient) RedirectURIList() []string { return splitList(c.RedirectURIs) }
+func (c *OAuthClient) GrantTypeList() []string   { return splitList(c.GrantTypes) }
+func (c *OAuthClient) ScopeList() []string       { return splitList(c.Scopes) }
+
+// OAuthClientResponse 客户端响应
+type OAuthClientResponse struct {
+	*OAuthClient
+	Public       bool     `json:"public"`
+	RedirectURIs []string `json:"redirectUris"`
+	GrantTypes   []string `json:"grantTypes"`
+	Scopes       []string `json:"scopes"`
+	ClientSecret string   `json:"clientSecret,omitempty"` // 明文密钥，只在创建时返回
+}
+
+func (c *OAuthClient) ToResponse() *OAuthClientResponse {
+	return &OAuthClientResponse{
+		OAuthClient:  c,
+		Public:       c.Public(),
+		RedirectURIs: c.RedirectURIList(),
+		GrantTypes:   c.GrantTypeList(),
+		Scopes:       c.ScopeList(),
+	}
+}
+
+// CreateOAuthClientRequest 注册客户端请求
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=50"`
+	RedirectURIs []string `json:"redirectUris" binding:"dive,url"`
+	GrantTypes   []string `json:"grantTypes" binding:"required,min=1,dive,oneof=authorization_code client_credentials"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,dive,required,max=100"`
+	Public       bool     `json:"public"` // 公开客户端不生成密钥
+}
+
+// DeleteOAuthClientRequest 删除客户端请求
+type DeleteOAuthClientRequest struct {
+	ID int64 `json:"id" binding:"required"`
+}
+
+// OAuthAuthorizeRequest 用户在前端同意授权后提交的授权请求，参数与标准授权请求一致
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `json:"responseType" binding:"required,eq=code"`
+	ClientId            string `json:"clientId" binding:"required"`
+	RedirectURI         string `json:"redirectUri" binding:"required"`
+	Scope               string `json:"scope" binding:"required"` // 空格分隔
+	State               string `json:"state"`
+	Nonce               string `json:"nonce"`
+	CodeChallenge       string `json:"codeChallenge" binding:"required"`
+	CodeChallengeMethod string `json:"codeChallengeMethod" binding:"required,eq=S256"`
+}
+
+// OAuthAuthorizeResponse 授权结果，前端跳转到 redirectUrl
+type OAuthAuthorizeResponse struct {
+	RedirectURL string `json:"redirectUrl"`
+}
+
+// OAuthTokenResponse 令牌接口响应（RFC 6749 5.1）
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+	IDToken     string `json:"id_token,omitempty"`
+}
+
+// OAuthIntrospection 令牌内省响应（RFC 7662）
+type OAuthIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientId  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Passkey 用户注册的 WebAuthn 凭据（通行密钥）
+type Passkey struct {
+	ID              int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId          int64      `gorm:"column:user_id" json:"userId"`
+	Name            string     `gorm:"column:name" json:"name"`
+	CredentialId    string     `gorm:"column:credential_id" json:"credentialId"` // base64url 编码的凭据 ID
+	PublicKey       []byte     `gorm:"column:public_key" json:"-"`               // COSE 格式公钥
+	AttestationType string     `gorm:"column:attestation_type" json:"-"`
+	AAGUID          []byte     `gorm:"column:aaguid" json:"-"` // 认证器型号标识
+	SignCount       uint32     `gorm:"column:sign_count" json:"signCount"`
+	Transports      string     `gorm:"column:transports" json:"-"` // 逗号分隔
+	BackupEligible  bool       `gorm:"column:backup_eligible" json:"backupEligible"`
+	BackupState     bool       `gorm:"column:backup_state" json:"backupState"` // 是否已同步到云端（多设备通行密钥）
+	LastUsedTime    *time.Time `gorm:"column:last_used_time" json:"lastUsedTime"`
+	CreateTime      time.Time  `gorm:"column:create_time;autoCreateTime" json:"createTime"`
+}
+
+func (*Passkey) TableName() string {
+	return "user_passkey"
+}
+
+// PasskeyRegisterRequest 注册通行密钥前再次确认身份：提交当前密码，开启两步验证的用户也可以提交验证码
+type PasskeyRegisterRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// PasskeyRegisterResponse 注册选项，前端原样传给 navigator.credentials.create()
+type PasskeyRegisterResponse struct {
+	Options any `json:"options"`
+}
+
+// PasskeyConfirmRequest 提交认证器返回的注册结果
+type PasskeyConfirmRequest struct {
+	Name       string          `json:"name" binding:"required,max=50"`
+	Credential json.RawMessage `json:"credential" binding:"required"` // navigator.credentials.create() 返回的 PublicKeyCredential
+}
+
+// DeletePasskeyRequest 删除通行密钥请求
+type DeletePasskeyRequest struct {
+	ID int64 `json:"id" binding:"required"`
+}
+
+// PasskeyLoginOptionsResponse 登录选项，前端原样传给 navigator.credentials.get()
+type PasskeyLoginOptionsResponse struct {
+	SessionId string `json:"sessionId"` // 本次登录仪式的标识，提交登录结果时原样带回
+	Options   any    `json:"options"`
+}
+
+// PasskeyLoginRequest 提交认证器返回的断言完成登录
+type PasskeyLoginRequest struct {
+	SessionId  string          `json:"sessionId" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"` // navigator.credentials.get() 返回的 PublicKeyCredential
+}
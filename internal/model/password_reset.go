@@ -0,0 +1,12 @@
+package model
+
+// PasswordResetRequest 申请重置密码请求
+type PasswordResetRequest struct {
+	Username string `json:"username" binding:"required"` // 用户名或邮箱
+}
+
+// PasswordResetConfirmRequest 确认重置密码请求
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
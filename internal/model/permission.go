@@ -9,3 +9,14 @@ type Permission struct {
 func (*Permission) TableName() string {
 	return "permission"
 }
+
+// CreatePermissionRequest 创建权限请求
+type CreatePermissionRequest struct {
+	Permit string `json:"permit" binding:"required,max=100"`
+	Name   string `json:"name" binding:"max=50"`
+}
+
+// DeletePermissionRequest 删除权限请求
+type DeletePermissionRequest struct {
+	ID int64 `json:"id" binding:"required"`
+}
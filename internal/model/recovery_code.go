@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// RecoveryCode 两步验证恢复码，只保存摘要
+type RecoveryCode struct {
+	ID       int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId   int64      `gorm:"column:user_id" json:"userId"`
+	CodeHash string     `gorm:"column:code_hash" json:"-"`
+	UsedTime *time.Time `gorm:"column:used_time" json:"usedTime"` // 为空表示未使用
+}
+
+func (*RecoveryCode) TableName() string {
+	return "user_recovery_code"
+}
@@ -0,0 +1,40 @@
+package model
+
+// Role 角色模型
+type Role struct {
+	ID   int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Code string `gorm:"column:code" json:"code"`
+	Name string `gorm:"column:name" json:"name"`
+}
+
+func (*Role) TableName() string {
+	return "role"
+}
+
+// CreateRoleRequest 创建角色请求
+type CreateRoleRequest struct {
+	Code string `json:"code" binding:"required,max=50"`
+	Name string `json:"name" binding:"max=50"`
+}
+
+// DeleteRoleRequest 删除角色请求
+type DeleteRoleRequest struct {
+	ID int64 `json:"id" binding:"required"`
+}
+
+// RolePermissionRequest 角色授权/取消授权请求
+type RolePermissionRequest struct {
+	RoleId       int64 `json:"roleId" binding:"required"`
+	PermissionId int64 `json:"permissionId" binding:"required"`
+}
+
+// ListRolePermissionsRequest 查询角色权限请求
+type ListRolePermissionsRequest struct {
+	RoleId int64 `json:"roleId" binding:"required"`
+}
+
+// UserRoleRequest 用户分配/移除角色请求
+type UserRoleRequest struct {
+	UserId int64 `json:"userId" binding:"required"`
+	RoleId int64 `json:"roleId" binding:"required"`
+}
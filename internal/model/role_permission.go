@@ -0,0 +1,10 @@
+package model
+
+type RolePermission struct {
+	RoleId       int64 `gorm:"column:role_id" json:"roleId"`
+	PermissionId int64 `gorm:"column:permission_id" json:"permissionId"`
+}
+
+func (*RolePermission) TableName() string {
+	return "role_permission"
+}
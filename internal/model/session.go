@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// ClientInfo 发起登录的客户端信息
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// Session 登录会话，对应一个刷新令牌家族
+type Session struct {
+	ID           string    `json:"id"`
+	IP           string    `json:"ip"` // 登录时的 IP
+	UserAgent    string    `json:"userAgent"`
+	CreateTime   time.Time `json:"createTime"`
+	LastSeenTime time.Time `json:"lastSeenTime"`
+	LastSeenIP   string    `json:"lastSeenIp"` // 最近一次活跃时的 IP
+	Current      bool      `json:"current"`    // 是否为发起请求的会话
+}
+
+// RevokeSessionRequest 吊销单个会话请求
+type RevokeSessionRequest struct {
+	ID string `json:"id" binding:"required"`
+}
@@ -0,0 +1,23 @@
+package model
+
+// TokenResponse 令牌响应
+type TokenResponse struct {
+	Token        string `json:"token"`        // 访问令牌
+	RefreshToken string `json:"refreshToken"` // 刷新令牌
+	ExpiresIn    int64  `json:"expiresIn"`    // 访问令牌有效期（秒）
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// LogoutRequest 退出登录请求
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"` // 可选，同时吊销该刷新令牌所属的令牌家族
+}
+
+// RevokeSessionsRequest 吊销用户全部会话请求
+type RevokeSessionsRequest struct {
+	ID int64 `json:"id" binding:"required"`
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 )
 
@@ -10,9 +11,16 @@ type User struct {
 	Username   string    `gorm:"column:username;type:varchar(50);not null;uniqueIndex:uk_username" json:"username"`
 	Password   string    `gorm:"column:password;type:varchar(255);not null" json:"-"` // json:"-" 表示不返回密码
 	NikeName   string    `gorm:"column:nike_name;type:varchar(50)" json:"nikeName"`
+	Email      string    `gorm:"column:email;type:varchar(100);default:null" json:"email"`
+	Verified   bool      `gorm:"column:email_verified;default:false" json:"verified"` // 邮箱是否已验证
 	CreateTime time.Time `gorm:"column:create_time;autoCreateTime" json:"createTime"`
 	UpdateTime time.Time `gorm:"column:update_time;autoUpdateTime" json:"updateTime"`
 	Delete     int       `gorm:"column:delete;type:tinyint(1);default:0" json:"-"` // 0-未删除 1-已删除
+
+	PasswordChangeTime *time.Time `gorm:"column:password_change_time" json:"-"` // 最近一次修改密码的时间
+	TotpSecret         string     `gorm:"column:totp_secret;default:null" json:"-"`
+	TotpEnabled        bool       `gorm:"column:totp_enabled;default:false" json:"-"` // 是否开启 TOTP 两步验证
+	PendingEmail       string     `gorm:"column:pending_email;default:null" json:"-"` // 待验证的邮箱，验证通过后才写入 email，未验证的地址不会占用他人的邮箱
 }
 
 // TableName 指定表名
@@ -22,22 +30,44 @@ func (*User) TableName() string {
 
 // LoginRequest 登录请求
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
+	Username string `json:"username" binding:"required"` // 用户名或邮箱
 	Password string `json:"password" binding:"required"`
 }
 
 // RegisterRequest 注册请求
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Password string `json:"password" binding:"required,min=6,max=50"`
+	Username string `json:"username" binding:"required,min=3,max=50,excludes=@"` // 不能包含 @，登录时含 @ 的账号按邮箱匹配
+	Password string `json:"password" binding:"required"`                         // 密码规则见 password_policy 配置
 	NikeName string `json:"nikeName" binding:"max=50"`
+	Email    string `json:"email" binding:"omitempty,email,max=100"`
 }
 
 // UpdateUserRequest 更新用户请求
 type UpdateUserRequest struct {
 	ID       int64  `json:"id" binding:"required"`
 	NikeName string `json:"nikeName" binding:"max=50"`
-	Password string `json:"password" binding:"omitempty,min=6,max=50"`
+	Password string `json:"password"` // 管理员重置密码，只凭 self_permits 修改自己时不允许设置
+}
+
+// UpdateProfileRequest 更新个人资料请求
+type UpdateProfileRequest struct {
+	NikeName string `json:"nikeName" binding:"required,max=50"`
+}
+
+// ChangeEmailRequest 修改邮箱请求
+type ChangeEmailRequest struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+}
+
+// VerifyEmailRequest 验证邮箱请求
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
 }
 
 // GetUserListRequest 获取用户列表请求
@@ -51,6 +81,11 @@ type GetUserByIDRequest struct {
 	ID int64 `json:"id" binding:"required"`
 }
 
+// UnlockUserRequest 解锁用户请求
+type UnlockUserRequest struct {
+	ID int64 `json:"id" binding:"required"`
+}
+
 // DeleteUserRequest 删除用户请求
 type DeleteUserRequest struct {
 	ID int64 `json:"id" binding:"required"`
@@ -61,8 +96,12 @@ type UserResponse struct {
 	ID         int64     `json:"id"`
 	Username   string    `json:"username"`
 	NikeName   string    `json:"nikeName"`
+	Email      string    `json:"email,omitempty"`
+	Verified   bool      `json:"verified"`
 	CreateTime time.Time `json:"createTime"`
 	UpdateTime time.Time `json:"updateTime"`
+
+	PendingEmail string `json:"pendingEmail,omitempty"` // 待验证的新邮箱
 }
 
 // maskUsername 用户名脱敏
@@ -88,13 +127,27 @@ func maskUsername(username string) string {
 	return masked
 }
 
-// ToResponse 转换为响应对象（用户名脱敏）
+// maskEmail 邮箱脱敏，只对 @ 前的部分脱敏
+// 例如：testuser@example.com -> t******r@example.com
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return maskUsername(email)
+	}
+	return maskUsername(email[:at]) + email[at:]
+}
+
+// ToResponse 转换为响应对象（用户名、邮箱脱敏）
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
 		ID:         u.ID,
 		Username:   maskUsername(u.Username),
 		NikeName:   u.NikeName,
+		Email:      maskEmail(u.Email),
+		Verified:   u.Verified,
 		CreateTime: u.CreateTime,
 		UpdateTime: u.UpdateTime,
+
+		PendingEmail: maskEmail(u.PendingEmail),
 	}
 }
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// UserIdentity 外部身份与本地用户的绑定关系
+type UserIdentity struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId     int64     `gorm:"column:user_id" json:"userId"`
+	Provider   string    `gorm:"column:provider" json:"provider"` // 身份提供方名称
+	Subject    string    `gorm:"column:subject" json:"subject"`   // 身份提供方中的用户标识（sub）
+	Email      string    `gorm:"column:email" json:"email"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime" json:"createTime"`
+}
+
+func (*UserIdentity) TableName() string {
+	return "user_identity"
+}
+
+// OIDCLoginRequest 发起单点登录请求
+type OIDCLoginRequest struct {
+	Provider string `json:"provider" binding:"required"`
+}
+
+// OIDCLoginResponse 发起单点登录响应，前端把 binding 保存在当前浏览器（如 sessionStorage）后跳转到 authUrl
+type OIDCLoginResponse struct {
+	AuthURL string `json:"authUrl"`
+	Binding string `json:"binding"`
+}
+
+// OIDCCallbackRequest 单点登录回调请求，code 和 state 来自身份提供方重定向到前端时携带的查询参数
+// binding 为发起登录时返回的值，确保回调由发起登录的同一浏览器提交，防止登录 CSRF
+type OIDCCallbackRequest struct {
+	Code    string `json:"code" binding:"required"`
+	State   string `json:"state" binding:"required"`
+	Binding string `json:"binding" binding:"required"`
+}
@@ -8,3 +8,14 @@ type UserPermission struct {
 func (*UserPermission) TableName() string {
 	return "user_permission"
 }
+
+// UserPermissionRequest 用户授权/取消授权请求
+type UserPermissionRequest struct {
+	UserId       int64 `json:"userId" binding:"required"`
+	PermissionId int64 `json:"permissionId" binding:"required"`
+}
+
+// ListUserPermissionsRequest 查询用户权限请求
+type ListUserPermissionsRequest struct {
+	UserId int64 `json:"userId" binding:"required"`
+}
@@ -0,0 +1,10 @@
+package model
+
+type UserRole struct {
+	UserId int64 `gorm:"column:user_id" json:"userId"`
+	RoleId int64 `gorm:"column:role_id" json:"roleId"`
+}
+
+func (*UserRole) TableName() string {
+	return "user_role"
+}
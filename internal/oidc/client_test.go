@@ -0,0 +1,217 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"users-by-go-example/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIdP 本地模拟的身份提供方
+type mockIdP struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	kid      string
+	clientID string
+
+	// 授权码 -> 授权请求中的 code_challenge 和 nonce
+	codes  map[string][2]string
+	claims func(nonce string) jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &mockIdP{key: key, kid: "test-key", clientID: "app", codes: map[string][2]string{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": idp.kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		entry, ok := idp.codes[r.Form.Get("code")]
+		if !ok || CodeChallenge(r.Form.Get("code_verifier")) != entry[0] {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		delete(idp.codes, r.Form.Get("code"))
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"id_token":     idp.sign(t, idp.claims(entry[1])),
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	idp.claims = func(nonce string) jwt.MapClaims {
+		now := time.Now()
+		return jwt.MapClaims{
+			"iss":                idp.server.URL,
+			"sub":                "emp-001",
+			"aud":                idp.clientID,
+			"exp":                now.Add(5 * time.Minute).Unix(),
+			"iat":                now.Unix(),
+			"nonce":              nonce,
+			"email":              "alice@corp.example",
+			"email_verified":     true,
+			"preferred_username": "alice",
+		}
+	}
+	return idp
+}
+
+func (idp *mockIdP) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+	signed, err := token.SignedString(idp.key)
+	require.NoError(t, err)
+	return signed
+}
+
+// authorize 模拟用户在身份提供方完成登录，返回授权码
+func (idp *mockIdP) authorize(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	idp.codes["code-1"] = [2]string{q.Get("code_challenge"), q.Get("nonce")}
+	return "code-1"
+}
+
+func (idp *mockIdP) client(t *testing.T) *Client {
+	c, err := NewClient(&config.OIDCProviderConfig{
+		Name:        "corp",
+		Issuer:      idp.server.URL,
+		ClientID:    idp.clientID,
+		RedirectURL: "http://localhost:3000/sso/callback",
+	}, idp.server.Client())
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_AuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	c := idp.client(t)
+	ctx := context.Background()
+
+	verifier := "verifier-0123456789-0123456789-0123456789"
+	authURL, err := c.AuthCodeURL(ctx, "state-1", "nonce-1", CodeChallenge(verifier))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(authURL, idp.server.URL+"/authorize?"))
+	assert.Contains(t, authURL, "scope=openid+profile+email")
+
+	code := idp.authorize(t, authURL)
+	token, err := c.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+
+	claims, err := c.VerifyIDToken(ctx, token.IDToken, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "emp-001", claims.Subject)
+	assert.Equal(t, "alice", claims.Username(""))
+	assert.Equal(t, "alice@corp.example", claims.Username("email"))
+	assert.True(t, claims.EmailVerified)
+}
+
+func TestClient_ExchangeWrongVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	c := idp.client(t)
+	ctx := context.Background()
+
+	authURL, err := c.AuthCodeURL(ctx, "state-1", "nonce-1", CodeChallenge("right-verifier"))
+	require.NoError(t, err)
+	code := idp.authorize(t, authURL)
+
+	_, err = c.Exchange(ctx, code, "wrong-verifier")
+	assert.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestClient_VerifyIDTokenRejects(t *testing.T) {
+	idp := newMockIdP(t)
+	c := idp.client(t)
+	ctx := context.Background()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{"nonce 不匹配", func() string { return idp.sign(t, idp.claims("other-nonce")) }},
+		{"issuer 不匹配", func() string {
+			claims := idp.claims("nonce-1")
+			claims["iss"] = "https://evil.example"
+			return idp.sign(t, claims)
+		}},
+		{"audience 不匹配", func() string {
+			claims := idp.claims("nonce-1")
+			claims["aud"] = "other-app"
+			return idp.sign(t, claims)
+		}},
+		{"多个受众但 azp 不是本客户端", func() string {
+			claims := idp.claims("nonce-1")
+			claims["aud"] = []string{idp.clientID, "other-app"}
+			claims["azp"] = "other-app"
+			return idp.sign(t, claims)
+		}},
+		{"已过期", func() string {
+			claims := idp.claims("nonce-1")
+			claims["exp"] = time.Now().Add(-10 * time.Minute).Unix()
+			return idp.sign(t, claims)
+		}},
+		{"签名密钥不对", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims("nonce-1"))
+			token.Header["kid"] = idp.kid
+			signed, _ := token.SignedString(otherKey)
+			return signed
+		}},
+		{"不允许 HS256", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, idp.claims("nonce-1"))
+			signed, _ := token.SignedString([]byte("secret"))
+			return signed
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.VerifyIDToken(ctx, tt.token(), "nonce-1")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 附录 B 示例
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
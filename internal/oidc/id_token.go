@@ -0,0 +1,223 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IDTokenClaims ID Token 中使用到的声明
+type IDTokenClaims struct {
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     bool   `json:"email_verified,omitempty"`
+	Name              string `json:"name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// jsonWebKey JWKS 中的单个公钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet 缓存的身份提供方公钥
+type keySet struct {
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// 遇到未知 kid 时重新拉取公钥（身份提供方轮换了密钥），但限制频率避免被伪造的 kid 打爆
+const jwksRefreshInterval = time.Minute
+
+// VerifyIDToken 校验 ID Token：签名（按 kid 匹配身份提供方公钥）、iss、aud、azp、exp、iat 和 nonce
+func (c *Client) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDTokenClaims, error) {
+	d, err := c.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(c.conf.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ID Token 校验失败: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("ID Token 缺少 sub")
+	}
+	// 存在多个受众时 azp 必须是本客户端
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != c.conf.ClientID {
+		return nil, errors.New("ID Token azp 不匹配")
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("ID Token nonce 不匹配")
+	}
+	return claims, nil
+}
+
+// publicKey 按 kid 查找身份提供方公钥，未找到时刷新一次 JWKS
+func (c *Client) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	keys := c.keys
+	c.mu.Unlock()
+
+	if keys != nil {
+		if key, ok := keys.lookup(kid); ok {
+			return key, nil
+		}
+		if time.Since(keys.fetchedAt) < jwksRefreshInterval {
+			return nil, fmt.Errorf("unknown kid: %q", kid)
+		}
+	}
+
+	keys, err := c.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := keys.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown kid: %q", kid)
+}
+
+// lookup 按 kid 查找公钥；令牌未携带 kid 且只有一把公钥时直接使用
+func (ks *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+func (c *Client) fetchKeys(ctx context.Context) (*keySet, error) {
+	d, err := c.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := c.getJSON(ctx, d.JwksURI, &set); err != nil {
+		return nil, fmt.Errorf("获取 OIDC 公钥失败: %w", err)
+	}
+
+	keys := &keySet{keys: make(map[string]crypto.PublicKey), fetchedAt: time.Now()}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// 不认识的密钥类型直接跳过，不影响其他密钥
+		if key, err := jwk.publicKey(); err == nil {
+			keys.keys[jwk.Kid] = key
+		}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+	return keys, nil
+}
+
+// publicKey 解析 JWK 为公钥，支持 RSA、EC（P-256/P-384）和 Ed25519
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("Ed25519 公钥格式错误")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型: %s", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("JWK 参数格式错误")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// Username 按配置的声明取用户名，默认依次尝试 preferred_username、email
+func (c *IDTokenClaims) Username(claim string) string {
+	switch claim {
+	case "email":
+		return c.Email
+	case "sub":
+		return c.Subject
+	case "name":
+		return c.Name
+	}
+	return firstNonEmpty(c.PreferredUsername, c.Email)
+}
+
+func firstNonEmpty(values ...string) string {
+	i := slices.IndexFunc(values, func(s string) bool { return s != "" })
+	if i < 0 {
+		return ""
+	}
+	return values[i]
+}
@@ -0,0 +1,195 @@
+package permit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expr 权限表达式
+//
+// 语法（优先级从低到高）：
+//
+//	expr  = and { "|" and }
+//	and   = unary { ("&" | ",") unary }
+//	unary = "(" expr ")" | permit
+//
+// 例如 user:update | user:admin、(a & b) | c；逗号与 & 等价，兼容原有 "a,b" 写法
+type Expr interface {
+	// Eval 使用 has 判断单个权限标识是否满足，计算整个表达式
+	Eval(has func(permit string) bool) bool
+	String() string
+}
+
+// permitExpr 单个权限标识
+type permitExpr string
+
+func (e permitExpr) Eval(has func(string) bool) bool {
+	return has(string(e))
+}
+
+func (e permitExpr) String() string {
+	return string(e)
+}
+
+// andExpr 全部满足
+type andExpr []Expr
+
+func (e andExpr) Eval(has func(string) bool) bool {
+	for _, item := range e {
+		if !item.Eval(has) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e andExpr) String() string {
+	return joinExpr(e, " & ")
+}
+
+// orExpr 任一满足
+type orExpr []Expr
+
+func (e orExpr) Eval(has func(string) bool) bool {
+	for _, item := range e {
+		if item.Eval(has) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e orExpr) String() string {
+	return joinExpr(e, " | ")
+}
+
+func joinExpr(items []Expr, sep string) string {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		if _, ok := item.(permitExpr); ok {
+			parts = append(parts, item.String())
+		} else {
+			parts = append(parts, "("+item.String()+")")
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// Parse 解析权限表达式，空字符串返回 nil 表示不要求任何权限
+func Parse(input string) (Expr, error) {
+	p := &parser{input: input}
+	p.skipSpace()
+	if p.pos == len(p.input) {
+		return nil, nil
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.input) {
+		return nil, p.errorf("多余的字符 %q", p.input[p.pos])
+	}
+	return expr, nil
+}
+
+// parser 递归下降解析器
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	items := orExpr{first}
+	for p.consume('|') {
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, next)
+	}
+
+	if len(items) == 1 {
+		return first, nil
+	}
+	return items, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	items := andExpr{first}
+	for p.consume('&') || p.consume(',') {
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, next)
+	}
+
+	if len(items) == 1 {
+		return first, nil
+	}
+	return items, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.consume('(') {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(')') {
+			return nil, p.errorf("缺少右括号")
+		}
+		return expr, nil
+	}
+
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && isPermitChar(p.input[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos == len(p.input) {
+			return nil, p.errorf("表达式不完整，期望权限标识")
+		}
+		return nil, p.errorf("期望权限标识，实际为 %q", p.input[p.pos])
+	}
+	return permitExpr(p.input[start:p.pos]), nil
+}
+
+// consume 跳过空白后，如果下一个字符是 c 则消费它
+func (p *parser) consume(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.input) && p.input[p.pos] == c {
+		p.pos++
+		p.skipSpace()
+		return true
+	}
+	return false
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("权限表达式 %q 第 %d 个字符处: %s", p.input, p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// isPermitChar 权限标识允许的字符
+func isPermitChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == ':' || c == '*' || c == '_' || c == '-' || c == '.'
+}
@@ -0,0 +1,95 @@
+package permit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"单个权限", "user:get", "user:get"},
+		{"逗号兼容原有写法", "user:get,user:list", "user:get & user:list"},
+		{"逗号两侧空格", "user:get, user:list", "user:get & user:list"},
+		{"或", "user:update | user:admin", "user:update | user:admin"},
+		{"与优先于或", "a & b | c", "(a & b) | c"},
+		{"括号", "(a & b) | c", "(a & b) | c"},
+		{"括号改变优先级", "a & (b | c)", "a & (b | c)"},
+		{"通配权限", "user:* | *", "user:* | *"},
+		{"冗余括号", "((a))", "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, expr.String())
+		})
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	for _, input := range []string{"", "   "} {
+		expr, err := Parse(input)
+		assert.NoError(t, err)
+		assert.Nil(t, expr)
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"缺少右括号", "(a | b", "缺少右括号"},
+		{"多余右括号", "a | b)", "多余的字符"},
+		{"缺少右操作数", "a |", "表达式不完整"},
+		{"缺少左操作数", "| a", "期望权限标识"},
+		{"连续运算符", "a & | b", "期望权限标识"},
+		{"非法字符", "user:get!", "多余的字符"},
+		{"空括号", "()", "期望权限标识"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestExpr_Eval(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		granted []string
+		want    bool
+	}{
+		{"全部满足", "a,b", []string{"a", "b"}, true},
+		{"缺少其一", "a,b", []string{"a"}, false},
+		{"或满足其一", "user:update | user:admin", []string{"user:admin"}, true},
+		{"或均不满足", "user:update | user:admin", []string{"user:get"}, false},
+		{"组合左侧满足", "(a & b) | c", []string{"a", "b"}, true},
+		{"组合右侧满足", "(a & b) | c", []string{"c"}, true},
+		{"组合均不满足", "(a & b) | c", []string{"a"}, false},
+		{"配合通配匹配", "user:update | user:admin", []string{"user:*"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			assert.NoError(t, err)
+			got := expr.Eval(func(required string) bool {
+				return MatchAny(tt.granted, required)
+			})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
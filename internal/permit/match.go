@@ -0,0 +1,47 @@
+package permit
+
+import "strings"
+
+// separator 权限标识的分段符，例如 user:get
+const separator = ":"
+
+// wildcard 通配段，匹配任意一段；单独使用时匹配所有权限
+const wildcard = "*"
+
+// Match 判断已授予的权限标识 granted 是否满足所需的权限标识 required
+//
+// 匹配规则：
+//   - 按 ":" 分段逐段比较，granted 中的 "*" 匹配 required 中对应的任意一段，例如 user:* 匹配 user:get，*:list 匹配 role:list
+//   - granted 段数少于 required 时视为上级权限，按前缀匹配，例如 user 包含 user:get 和 user:get:detail
+//   - granted 段数多于 required 时不匹配，下级权限不能满足上级权限的要求
+//   - 不含通配符且段数相同时退化为精确匹配，与原有行为一致
+func Match(granted, required string) bool {
+	granted = strings.TrimSpace(granted)
+	required = strings.TrimSpace(required)
+	if granted == "" || required == "" {
+		return false
+	}
+
+	grantedParts := strings.Split(granted, separator)
+	requiredParts := strings.Split(required, separator)
+	if len(grantedParts) > len(requiredParts) {
+		return false
+	}
+
+	for i, part := range grantedParts {
+		if part != wildcard && part != requiredParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchAny 判断已授予的权限中是否有任意一个满足 required
+func MatchAny(granted []string, required string) bool {
+	for _, item := range granted {
+		if Match(item, required) {
+			return true
+		}
+	}
+	return false
+}
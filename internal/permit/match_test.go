@@ -0,0 +1,68 @@
+package permit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  string
+		required string
+		want     bool
+	}{
+		// 精确匹配（兼容原有行为）
+		{"精确匹配", "user:get", "user:get", true},
+		{"不同权限", "user:get", "user:list", false},
+		{"全局通配", "*", "user:get", true},
+		{"全局通配匹配多段", "*", "user:get:detail", true},
+
+		// 分段通配
+		{"末段通配", "user:*", "user:get", true},
+		{"末段通配不匹配其他资源", "user:*", "role:get", false},
+		{"首段通配", "*:list", "user:list", true},
+		{"首段通配不匹配其他操作", "*:list", "user:get", false},
+		{"中间段通配", "user:*:detail", "user:get:detail", true},
+		{"通配段后继续前缀匹配", "user:*", "user:get:detail", true},
+
+		// 层级权限
+		{"上级权限包含下级", "user", "user:get", true},
+		{"上级权限包含多级下级", "user", "user:get:detail", true},
+		{"上级权限不匹配其他资源", "user", "users:get", false},
+		{"下级权限不满足上级要求", "user:get", "user", false},
+		{"通配段数多于要求", "user:*", "user", false},
+
+		// 边界情况
+		{"空授予", "", "user:get", false},
+		{"空要求", "user:get", "", false},
+		{"忽略首尾空格", " user:get ", "user:get", true},
+		{"通配符不做子串匹配", "us*", "user:get", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Match(tt.granted, tt.required))
+		})
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  []string
+		required string
+		want     bool
+	}{
+		{"无权限", nil, "user:get", false},
+		{"任一匹配", []string{"role:list", "user:*"}, "user:get", true},
+		{"均不匹配", []string{"role:list", "user:get"}, "user:delete", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchAny(tt.granted, tt.required))
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"log"
+	"users-by-go-example/internal/application"
 	"users-by-go-example/internal/handler"
 	"users-by-go-example/internal/middleware"
 
@@ -11,8 +13,29 @@ import (
 func SetupRouter() *gin.Engine {
 	router := gin.Default()
 
+	// 只信任配置的反向代理传递的 X-Forwarded-For，否则客户端可以伪造 IP 绕过按 IP 的限流和登录锁定
+	if err := router.SetTrustedProxies(application.GetConfig().Server.TrustedProxies); err != nil {
+		log.Fatalf("server.trusted-proxies 配置错误: %v", err)
+	}
+
 	// 创建用户处理器
 	userHandler := handler.NewUserHandler()
+	roleHandler := handler.NewRoleHandler()
+	permissionHandler := handler.NewPermissionHandler()
+	meHandler := handler.NewMeHandler()
+	mfaHandler := handler.NewMfaHandler()
+	passkeyHandler := handler.NewPasskeyHandler()
+	apiKeyHandler := handler.NewApiKeyHandler()
+	sessionHandler := handler.NewSessionHandler()
+	impersonationHandler := handler.NewImpersonationHandler()
+	oidcHandler := handler.NewOIDCHandler()
+	oauthHandler := handler.NewOAuthHandler()
+	passwordResetHandler := handler.NewPasswordResetHandler()
+	wellKnownHandler := handler.NewWellKnownHandler()
+
+	// 公开的验签公钥
+	router.GET("/.well-known/jwks.json", wellKnownHandler.JWKS)
+	router.GET("/.well-known/openid-configuration", wellKnownHandler.OpenIDConfiguration)
 
 	// API v1 路由组
 	v1 := router.Group("/api/v1")
@@ -22,14 +45,81 @@ func SetupRouter() *gin.Engine {
 	// 公开接口
 	v1.POST("/register", userHandler.Register)
 	v1.POST("/login", userHandler.Login)
+	v1.POST("/login/mfa", mfaHandler.VerifyLogin)
+	v1.POST("/login/passkey/options", passkeyHandler.LoginOptions)
+	v1.POST("/login/passkey", passkeyHandler.Login)
+	v1.POST("/oidc/login", oidcHandler.Login)
+	v1.POST("/oidc/callback", oidcHandler.Callback)
+	v1.POST("/token/refresh", userHandler.RefreshToken)
+	v1.POST("/password/reset/request", passwordResetHandler.RequestReset)
+	v1.POST("/password/reset/confirm", passwordResetHandler.ConfirmReset)
+	v1.POST("/email/verify", userHandler.VerifyEmail)
+
+	// 授权服务器接口，由第三方应用使用客户端凭证或访问令牌调用
+	v1.POST("/oauth/token", oauthHandler.Token)
+	v1.POST("/oauth/introspect", oauthHandler.Introspect)
+	v1.GET("/oauth/userinfo", oauthHandler.UserInfo)
+	v1.POST("/oauth/userinfo", oauthHandler.UserInfo)
 
 	// 需要认证的接口（创建一个新的作用域，Use() 方法会将中间件应用到后续注册的所有路由上）
-	v1.Use(middleware.AuthorizationCheck(), middleware.PermissionCheck())
+	v1.Use(middleware.AuthorizationCheck(), middleware.ImpersonationGuard(), middleware.PermissionCheck())
 
 	v1.POST("/users/list", userHandler.GetUserList)
 	v1.POST("/users/get", userHandler.GetUserByID)
 	v1.POST("/users/update", userHandler.UpdateUser)
 	v1.POST("/users/delete", userHandler.DeleteUser)
+	v1.POST("/users/revoke-sessions", userHandler.RevokeSessions)
+	v1.POST("/users/unlock", userHandler.UnlockUser)
+
+	v1.POST("/impersonation/start", impersonationHandler.Start)
+	v1.POST("/impersonation/logs", impersonationHandler.ListLogs)
+
+	v1.POST("/permissions/list", permissionHandler.ListPermissions)
+	v1.POST("/permissions/create", permissionHandler.CreatePermission)
+	v1.POST("/permissions/delete", permissionHandler.DeletePermission)
+	v1.POST("/users/permits/list", permissionHandler.ListUserPermissions)
+	v1.POST("/users/permits/grant", permissionHandler.GrantUserPermission)
+	v1.POST("/users/permits/revoke", permissionHandler.RevokeUserPermission)
+
+	v1.POST("/roles/list", roleHandler.ListRoles)
+	v1.POST("/roles/create", roleHandler.CreateRole)
+	v1.POST("/roles/delete", roleHandler.DeleteRole)
+	v1.POST("/roles/permits/list", roleHandler.ListRolePermissions)
+	v1.POST("/roles/permits/grant", roleHandler.GrantPermission)
+	v1.POST("/roles/permits/revoke", roleHandler.RevokePermission)
+	v1.POST("/users/roles/assign", roleHandler.AssignRole)
+	v1.POST("/users/roles/remove", roleHandler.RemoveRole)
+
+	v1.POST("/api-keys/create", apiKeyHandler.Create)
+	v1.POST("/api-keys/list", apiKeyHandler.List)
+	v1.POST("/api-keys/revoke", apiKeyHandler.Revoke)
+
+	v1.POST("/oauth/clients/create", oauthHandler.CreateClient)
+	v1.POST("/oauth/clients/list", oauthHandler.ListClients)
+	v1.POST("/oauth/clients/delete", oauthHandler.DeleteClient)
+	v1.POST("/oauth/authorize", oauthHandler.Authorize)
+
+	v1.POST("/logout", userHandler.Logout)
+
+	v1.POST("/me/get", meHandler.GetProfile)
+	v1.POST("/me/update", meHandler.UpdateProfile)
+	v1.POST("/me/email", meHandler.ChangeEmail)
+	v1.POST("/me/email/verify", meHandler.SendEmailVerification)
+	v1.POST("/me/password", meHandler.ChangePassword)
+	v1.POST("/me/deactivate", meHandler.Deactivate)
+	v1.POST("/me/totp/enroll", mfaHandler.Enroll)
+	v1.POST("/me/totp/confirm", mfaHandler.Confirm)
+	v1.POST("/me/totp/disable", mfaHandler.Disable)
+	v1.POST("/me/passkeys/register", passkeyHandler.Register)
+	v1.POST("/me/passkeys/confirm", passkeyHandler.Confirm)
+	v1.POST("/me/passkeys/list", passkeyHandler.List)
+	v1.POST("/me/passkeys/delete", passkeyHandler.Delete)
+	v1.POST("/me/api-keys/create", apiKeyHandler.CreateMine)
+	v1.POST("/me/api-keys/list", apiKeyHandler.ListMine)
+	v1.POST("/me/api-keys/revoke", apiKeyHandler.RevokeMine)
+	v1.POST("/me/sessions/list", sessionHandler.List)
+	v1.POST("/me/sessions/revoke", sessionHandler.Revoke)
+	v1.POST("/me/sessions/revoke-others", sessionHandler.RevokeOthers)
 
 	return router
 }
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/permit"
+	"users-by-go-example/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyPrefix        = "ugk_"          // 明文密钥前缀，便于在日志和代码中识别泄露的密钥
+	apiKeyUsedKeyPrefix = "api_key_used:" // 最近使用时间的写库节流标记
+	apiKeyUsedInterval  = time.Minute
+)
+
+var ErrApiKeyInvalid = errors.New("API Key 无效、已过期或已吊销")
+
+// ApiKeyService API 密钥服务
+type ApiKeyService struct{}
+
+// Create 创建 API 密钥，scopes 必须是所属用户当前有效权限的子集
+func (s *ApiKeyService) Create(userId int64, keyType string, req *model.CreateApiKeyRequest) (*model.ApiKeyResponse, error) {
+	db := application.GetDB()
+
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+
+	permits, _, err := (&PermissionService{}).GetCachedUserPermits(userId)
+	if err != nil {
+		return nil, err
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scope = strings.TrimSpace(scope)
+		if strings.Contains(scope, ",") {
+			return nil, fmt.Errorf("权限标识 %s 格式错误", scope)
+		}
+		if !permit.MatchAny(permits, scope) {
+			return nil, fmt.Errorf("用户不具备权限 %s，不能授予 API Key", scope)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	raw = apiKeyPrefix + raw
+
+	key := &model.ApiKey{
+		UserId:  userId,
+		Name:    req.Name,
+		Type:    keyType,
+		Prefix:  raw[:12],
+		KeyHash: utils.HashToken(raw),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if req.ExpireDays > 0 {
+		expireTime := time.Now().AddDate(0, 0, req.ExpireDays)
+		key.ExpireTime = &expireTime
+	}
+	if err := db.Create(key).Error; err != nil {
+		return nil, err
+	}
+
+	resp := key.ToResponse()
+	resp.Key = raw
+	return resp, nil
+}
+
+// List 查询用户的 API 密钥，userId 为 0 时查询全部
+func (s *ApiKeyService) List(userId int64) ([]*model.ApiKeyResponse, error) {
+	db := application.GetDB()
+
+	query := db.Order("id DESC")
+	if userId > 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+
+	var keys []*model.ApiKey
+	if err := query.Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]*model.ApiKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		responses = append(responses, key.ToResponse())
+	}
+	return responses, nil
+}
+
+// Revoke 吊销 API 密钥，userId 不为 0 时只能吊销该用户自己的密钥
+func (s *ApiKeyService) Revoke(id, userId int64) error {
+	db := application.GetDB()
+
+	query := db.Model(&model.ApiKey{}).Where("id = ?", id)
+	if userId > 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	result := query.Update("revoked", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("API Key 不存在")
+	}
+	return nil
+}
+
+// Authenticate 校验明文密钥，返回密钥和所属用户
+func (s *ApiKeyService) Authenticate(raw string) (*model.ApiKey, *model.User, error) {
+	db := application.GetDB()
+
+	if !strings.HasPrefix(raw, apiKeyPrefix) {
+		return nil, nil, ErrApiKeyInvalid
+	}
+
+	var key model.ApiKey
+	if err := db.Where("key_hash = ?", utils.HashToken(raw)).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrApiKeyInvalid
+		}
+		return nil, nil, err
+	}
+	if key.Revoked || (key.ExpireTime != nil && key.ExpireTime.Before(time.Now())) {
+		return nil, nil, ErrApiKeyInvalid
+	}
+
+	var user model.User
+	if err := db.Select("id,username").Where("id = ? AND `delete` = 0", key.UserId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrApiKeyInvalid
+		}
+		return nil, nil, err
+	}
+
+	s.touch(&key)
+	return &key, &user, nil
+}
+
+// touch 记录最近使用时间，每个密钥每分钟最多写一次库
+func (s *ApiKeyService) touch(key *model.ApiKey) {
+	ok, err := application.GetRedis().SetNX(context.Background(), fmt.Sprintf("%s%d", apiKeyUsedKeyPrefix, key.ID), 1, apiKeyUsedInterval).Result()
+	if err != nil || !ok {
+		return
+	}
+	application.GetDB().Model(key).Update("last_used_time", time.Now())
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+	"users-by-go-example/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiKey_RevokedWithSessions(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &ApiKeyService{}
+	user := createPasswordUser(t, env, "alice", "s3cret-Passw0rd", "user:get")
+
+	create := func(keyType string) string {
+		resp, err := s.Create(user.ID, keyType, &model.CreateApiKeyRequest{Name: keyType, Scopes: []string{"user:get"}})
+		require.NoError(t, err)
+		_, _, err = s.Authenticate(resp.Key)
+		require.NoError(t, err)
+		return resp.Key
+	}
+	valid := func(raw string) bool {
+		_, _, err := s.Authenticate(raw)
+		if err != nil {
+			require.ErrorIs(t, err, ErrApiKeyInvalid)
+		}
+		return err == nil
+	}
+
+	// 修改密码后个人密钥失效，管理员创建的服务密钥不受影响
+	personal := create(model.ApiKeyTypePersonal)
+	service := create(model.ApiKeyTypeService)
+	_, err := (&UserService{}).ChangePassword(user.ID, &model.ChangePasswordRequest{OldPassword: "s3cret-Passw0rd", NewPassword: "n3w-Passw0rd"}, &model.ClientInfo{})
+	require.NoError(t, err)
+	assert.False(t, valid(personal))
+	assert.True(t, valid(service))
+
+	// 通过邮件重置密码后失效
+	personal = create(model.ApiKeyTypePersonal)
+	require.NoError(t, env.Redis.Set(passwordResetKeyPrefix+utils.HashToken("reset-token"), fmt.Sprint(user.ID)))
+	require.NoError(t, (&PasswordResetService{}).ConfirmReset(&model.PasswordResetConfirmRequest{Token: "reset-token", NewPassword: "r3set-Passw0rd"}))
+	assert.False(t, valid(personal))
+
+	// 管理员吊销全部会话后失效
+	personal = create(model.ApiKeyTypePersonal)
+	require.NoError(t, (&TokenService{}).RevokeAllSessions(user.ID))
+	assert.False(t, valid(personal))
+	assert.True(t, valid(service))
+}
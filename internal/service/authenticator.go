@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/ldapauth"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/utils"
+
+	"gorm.io/gorm"
+)
+
+// ldapProvider LDAP 用户在 user_identity 中的 provider
+const ldapProvider = "ldap"
+
+var (
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	// ErrLDAPManagedPassword 目录用户的密码由 LDAP 管理，不能设置本地密码
+	ErrLDAPManagedPassword = errors.New("该用户的密码由 LDAP 目录管理，不能设置本地密码")
+	// ErrAuthBackendUnavailable 外部目录服务不可用，且没有其他校验器通过
+	ErrAuthBackendUnavailable = errors.New("登录服务暂不可用，请稍后重试")
+
+	errAuthenticatorSkip = errors.New("用户不由该校验器管理")
+)
+
+// Authenticator 登录凭据校验器，Login 按 auth.chain 配置的顺序依次尝试
+type Authenticator interface {
+	// Authenticate 校验通过时返回本地用户；用户不归该校验器管理时返回 errAuthenticatorSkip
+	Authenticate(username, password string) (*model.User, error)
+}
+
+// authenticators 可用的校验器，auth.chain 中的名称在启动时已校验
+func authenticators() map[string]Authenticator {
+	return map[string]Authenticator{
+		"local": &LocalAuthenticator{},
+		"ldap":  &LDAPAuthenticator{},
+	}
+}
+
+// authenticate 依次尝试校验链中的校验器，返回通过校验的用户和校验器名称
+// 密码错误时继续尝试下一个；目录服务出错也继续尝试，全部未通过时返回 ErrAuthBackendUnavailable，不计入登录失败
+func authenticate(username, password string) (*model.User, string, error) {
+	// 未配置时只校验本地密码
+	chain := application.GetConfig().Auth.Chain
+	if len(chain) == 0 {
+		chain = []string{"local"}
+	}
+	available := authenticators()
+
+	var backendErr error
+	for _, name := range chain {
+		user, err := available[name].Authenticate(username, password)
+		if err == nil {
+			return user, name, nil
+		}
+		if errors.Is(err, errAuthenticatorSkip) || errors.Is(err, ErrInvalidCredentials) {
+			continue
+		}
+		backendErr = fmt.Errorf("%w: %s: %v", ErrAuthBackendUnavailable, name, err)
+	}
+	if backendErr != nil {
+		return nil, "", backendErr
+	}
+	return nil, "", ErrInvalidCredentials
+}
+
+// LocalAuthenticator 校验本地密码，旧算法或旧参数生成的哈希在校验通过时透明升级
+type LocalAuthenticator struct{}
+
+func (a *LocalAuthenticator) Authenticate(username, password string) (*model.User, error) {
+	var user model.User
+	query := application.GetDB().Model(&model.User{}).Select("id, username, password, email_verified, totp_enabled")
+	if err := whereLoginName(query, username).Scan(&user).Error; err != nil {
+		return nil, err
+	}
+	// 不存在的用户和没有本地密码的外部目录用户交给后续校验器，同样做一次哈希校验，不暴露用户是否存在
+	if user.ID == 0 || user.Password == "" {
+		utils.VerifyDummyPassword(password)
+		return nil, errAuthenticatorSkip
+	}
+	// 目录用户即使存在本地密码也不校验，只能通过 LDAP 登录，目录中停用的账号不会因此绕过目录
+	managed, err := ldapManaged(application.GetDB(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if managed {
+		utils.VerifyDummyPassword(password)
+		return nil, errAuthenticatorSkip
+	}
+
+	ok, needsRehash, _ := utils.VerifyPassword(user.Password, password)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	// 升级失败不影响本次登录
+	if needsRehash {
+		(&UserService{}).rehashPassword(&user, password)
+	}
+	return &user, nil
+}
+
+// ldapManaged 用户是否由 LDAP 目录创建，目录用户的密码只能在目录中修改
+func ldapManaged(db *gorm.DB, userId int64) (bool, error) {
+	var count int64
+	if err := db.Model(&model.UserIdentity{}).Where("user_id = ? AND provider = ?", userId, ldapProvider).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// LDAPAuthenticator 通过 LDAP / Active Directory 校验密码，首次登录时创建本地用户，每次登录按组映射同步角色和权限
+type LDAPAuthenticator struct{}
+
+func (a *LDAPAuthenticator) Authenticate(username, password string) (*model.User, error) {
+	directory := application.GetLDAPDirectory()
+	if directory == nil {
+		return nil, errAuthenticatorSkip
+	}
+
+	entry, err := directory.Authenticate(username, password)
+	if err != nil {
+		switch {
+		case errors.Is(err, ldapauth.ErrUserNotFound):
+			return nil, errAuthenticatorSkip
+		case errors.Is(err, ldapauth.ErrInvalidCredentials):
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	user, err := a.findOrProvisionUser(entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.syncGroups(user.ID, entry); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// findOrProvisionUser 按目录中的登录名查找绑定的本地用户，未绑定时创建
+// 本地同名用户不会自动绑定，避免通过目录账号接管已有账号
+func (a *LDAPAuthenticator) findOrProvisionUser(entry *ldapauth.User) (*model.User, error) {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	// 以登录名而不是 DN 作为标识，用户在目录中移动 OU 后仍能对应到同一个本地用户
+	subject := strings.ToLower(entry.Username)
+
+	lock := utils.NewRedisLock(rdb, "ldap:"+subject, 10*time.Second)
+	if err := lock.TryLock(ctx, 3, 100*time.Millisecond); err != nil {
+		if errors.Is(err, utils.ErrLockFailed) {
+			return nil, errors.New("系统繁忙，请稍后重试")
+		}
+		return nil, err
+	}
+	defer lock.Unlock(ctx)
+
+	var identity model.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", ldapProvider, subject).First(&identity).Error
+	if err == nil {
+		var user model.User
+		if err := db.Where("id = ? AND `delete` = 0", identity.UserId).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("用户不存在")
+			}
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 目录用户没有本地密码，只能通过 LDAP 登录
+	user := &model.User{NikeName: entry.DisplayName}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		username, err := availableUsername(tx, entry.Username)
+		if err != nil {
+			return err
+		}
+		user.Username = username
+
+		// 目录中的邮箱由组织维护，视为已验证；已被其他用户使用时不设置
+		if entry.Email != "" {
+			var count int64
+			if err := tx.Model(&model.User{}).Where("email = ?", entry.Email).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				user.Email = entry.Email
+				user.Verified = true
+			}
+		}
+
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.UserIdentity{
+			UserId:   user.ID,
+			Provider: ldapProvider,
+			Subject:  subject,
+			Email:    entry.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// syncGroups 按 ldap.group-mappings 同步用户的角色和权限
+// 映射中出现的角色和权限由目录管理：属于对应组时授予，不再属于任何对应组时收回；未出现在映射中的授权不受影响
+func (a *LDAPAuthenticator) syncGroups(userId int64, entry *ldapauth.User) error {
+	db := application.GetDB()
+	mappings := application.GetConfig().LDAP.GroupMappings
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	var managedRoles, wantedRoles, managedPermits, wantedPermits []string
+	for _, mapping := range mappings {
+		managedRoles = append(managedRoles, mapping.Roles...)
+		managedPermits = append(managedPermits, mapping.Permits...)
+		if entry.InGroup(mapping.Group) {
+			wantedRoles = append(wantedRoles, mapping.Roles...)
+			wantedPermits = append(wantedPermits, mapping.Permits...)
+		}
+	}
+
+	var roles []*model.Role
+	if len(managedRoles) > 0 {
+		if err := db.Where("code IN ?", managedRoles).Find(&roles).Error; err != nil {
+			return err
+		}
+	}
+	var permissions []*model.Permission
+	if len(managedPermits) > 0 {
+		if err := db.Where("permit IN ?", managedPermits).Find(&permissions).Error; err != nil {
+			return err
+		}
+	}
+
+	changed := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, role := range roles {
+			var count int64
+			if err := tx.Model(&model.UserRole{}).Where("user_id = ? AND role_id = ?", userId, role.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			wanted := slices.Contains(wantedRoles, role.Code)
+			switch {
+			case wanted && count == 0:
+				if err := tx.Create(&model.UserRole{UserId: userId, RoleId: role.ID}).Error; err != nil {
+					return err
+				}
+				changed = true
+			case !wanted && count > 0:
+				if err := tx.Where("user_id = ? AND role_id = ?", userId, role.ID).Delete(&model.UserRole{}).Error; err != nil {
+					return err
+				}
+				changed = true
+			}
+		}
+
+		for _, permission := range permissions {
+			var count int64
+			if err := tx.Model(&model.UserPermission{}).Where("user_id = ? AND permission_id = ?", userId, permission.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			wanted := slices.Contains(wantedPermits, permission.Permit)
+			switch {
+			case wanted && count == 0:
+				if err := tx.Create(&model.UserPermission{UserId: userId, PermissionId: permission.ID}).Error; err != nil {
+					return err
+				}
+				changed = true
+			case !wanted && count > 0:
+				if err := tx.Where("user_id = ? AND permission_id = ?", userId, permission.ID).Delete(&model.UserPermission{}).Error; err != nil {
+					return err
+				}
+				changed = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		return invalidateUserPermits(userId)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+	"users-by-go-example/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLDAPManagedPassword(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	user := env.CreateUser(t, "alice")
+	require.NoError(t, env.DB.Create(&model.UserIdentity{UserId: user.ID, Provider: ldapProvider, Subject: "alice"}).Error)
+
+	// 不能通过管理员修改、修改密码或找回密码为目录用户设置本地密码
+	_, err := (&UserService{}).UpdateUser(user.ID, &model.UpdateUserRequest{ID: user.ID, Password: "n3w-Passw0rd"})
+	assert.ErrorIs(t, err, ErrLDAPManagedPassword)
+	_, err = (&UserService{}).ChangePassword(user.ID, &model.ChangePasswordRequest{OldPassword: "-", NewPassword: "n3w-Passw0rd"}, &model.ClientInfo{})
+	assert.ErrorIs(t, err, ErrLDAPManagedPassword)
+
+	require.NoError(t, env.Redis.Set(passwordResetKeyPrefix+utils.HashToken("reset-token"), fmt.Sprint(user.ID)))
+	err = (&PasswordResetService{}).ConfirmReset(&model.PasswordResetConfirmRequest{Token: "reset-token", NewPassword: "n3w-Passw0rd"})
+	assert.ErrorIs(t, err, ErrLDAPManagedPassword)
+
+	var stored model.User
+	require.NoError(t, env.DB.First(&stored, user.ID).Error)
+	assert.Equal(t, "-", stored.Password)
+
+	// 已经存在的本地密码也不被 local 校验器接受
+	hashed, err := utils.HashPassword("old-Passw0rd")
+	require.NoError(t, err)
+	require.NoError(t, env.DB.Model(user).Update("password", hashed).Error)
+	_, err = (&LocalAuthenticator{}).Authenticate("alice", "old-Passw0rd")
+	assert.ErrorIs(t, err, errAuthenticatorSkip)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/mailer"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const emailVerifyKeyPrefix = "email_verify:" // 邮箱验证令牌，key 为令牌摘要
+
+var ErrEmailTokenInvalid = errors.New("验证链接无效或已过期")
+
+// EmailService 邮箱服务
+type EmailService struct{}
+
+// ChangeEmail 修改邮箱：新邮箱先作为待验证邮箱保存，验证通过后才替换当前邮箱
+func (s *EmailService) ChangeEmail(userId int64, email string) error {
+	db := application.GetDB()
+
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		return err
+	}
+	if strings.EqualFold(user.Email, email) {
+		return errors.New("新邮箱与当前邮箱相同")
+	}
+	if err := checkEmailAvailable(db, email, userId); err != nil {
+		return err
+	}
+
+	if err := db.Model(&user).Update("pending_email", email).Error; err != nil {
+		return err
+	}
+
+	return s.SendVerification(userId)
+}
+
+// SendVerification 向用户待验证的邮箱发送验证邮件
+func (s *EmailService) SendVerification(userId int64) error {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	conf := application.GetConfig().User
+	ctx := context.Background()
+
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		return err
+	}
+	if user.PendingEmail == "" {
+		if user.Email != "" {
+			return errors.New("邮箱已验证")
+		}
+		return errors.New("尚未设置邮箱")
+	}
+
+	allowed, err := utils.AllowRequest(ctx, rdb, fmt.Sprintf("email_verify:user:%d", userId), 5, time.Hour)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("请求过于频繁，请稍后重试")
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	// 令牌绑定邮箱地址，邮箱再次修改后旧令牌自动失效
+	ttl := time.Duration(conf.EmailVerifyTTL) * time.Hour
+	if err := rdb.Set(ctx, emailVerifyKeyPrefix+utils.HashToken(token), fmt.Sprintf("%d:%s", user.ID, user.PendingEmail), ttl).Err(); err != nil {
+		return err
+	}
+
+	return application.GetMailer().Send(&mailer.Message{
+		To:      user.PendingEmail,
+		Subject: "验证邮箱",
+		Body: fmt.Sprintf("您好 %s：\n\n请在 %d 小时内打开以下链接完成邮箱验证：\n\n%s\n\n如果这不是您本人的操作，请忽略本邮件。",
+			user.Username, conf.EmailVerifyTTL, fmt.Sprintf(conf.EmailVerifyURL, token)),
+	})
+}
+
+// ConfirmVerification 使用验证令牌完成邮箱验证，令牌只能使用一次
+// 验证通过后待验证邮箱才成为用户的邮箱；期间该邮箱已被其他用户验证时拒绝
+func (s *EmailService) ConfirmVerification(token string) error {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	key := emailVerifyKeyPrefix + utils.HashToken(token)
+	var get *redis.StringCmd
+	if _, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	}); err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if get.Err() != nil {
+		return ErrEmailTokenInvalid
+	}
+
+	idPart, email, found := strings.Cut(get.Val(), ":")
+	if !found {
+		return ErrEmailTokenInvalid
+	}
+	userId, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil {
+		return ErrEmailTokenInvalid
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := checkEmailAvailable(tx, email, userId); err != nil {
+			return err
+		}
+		result := tx.Model(&model.User{}).
+			Where("id = ? AND pending_email = ? AND `delete` = 0", userId, email).
+			Updates(map[string]interface{}{
+				"email":          email,
+				"email_verified": true,
+				"pending_email":  nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrEmailTokenInvalid
+		}
+		return nil
+	})
+}
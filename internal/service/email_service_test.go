@@ -0,0 +1,81 @@
+package service
+
+import (
+	"regexp"
+	"testing"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var mailTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+// lastMailToken 最近一封邮件中的令牌
+func lastMailToken(t *testing.T, env *testenv.Env, to string) string {
+	t.Helper()
+	msg := env.Mailer.Last()
+	require.NotNil(t, msg)
+	require.Equal(t, to, msg.To)
+	match := mailTokenPattern.FindStringSubmatch(msg.Body)
+	require.Len(t, match, 2)
+	return match[1]
+}
+
+func TestEmailVerification_PendingEmail(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	emailService := &EmailService{}
+
+	alice, err := (&UserService{}).Register(&model.RegisterRequest{Username: "alice", Password: "s3cret-Passw0rd", Email: "a@example.com"})
+	require.NoError(t, err)
+	aliceToken := lastMailToken(t, env, "a@example.com")
+
+	// 未验证的邮箱不占用地址，也不能用于登录
+	var stored model.User
+	require.NoError(t, env.DB.First(&stored, alice.ID).Error)
+	assert.Empty(t, stored.Email)
+	assert.Equal(t, "a@example.com", stored.PendingEmail)
+	assert.Error(t, whereLoginName(env.DB, "a@example.com").First(&model.User{}).Error)
+
+	// 其他用户抢先填写同一个邮箱，但无法在真正的所有者之后完成验证
+	bob := env.CreateUser(t, "bob")
+	require.NoError(t, emailService.ChangeEmail(bob.ID, "a@example.com"))
+	bobToken := lastMailToken(t, env, "a@example.com")
+
+	require.NoError(t, emailService.ConfirmVerification(aliceToken))
+	assert.EqualError(t, emailService.ConfirmVerification(bobToken), "邮箱已被使用")
+
+	var found model.User
+	require.NoError(t, whereLoginName(env.DB, "a@example.com").First(&found).Error)
+	assert.Equal(t, alice.ID, found.ID)
+	assert.True(t, found.Verified)
+	assert.Empty(t, found.PendingEmail)
+
+	// 已验证的邮箱不能再被其他用户填写，修改邮箱期间原邮箱保持有效
+	assert.EqualError(t, emailService.ChangeEmail(bob.ID, "a@example.com"), "邮箱已被使用")
+	require.NoError(t, emailService.ChangeEmail(alice.ID, "new@example.com"))
+	require.NoError(t, env.DB.First(&stored, alice.ID).Error)
+	assert.Equal(t, "a@example.com", stored.Email)
+	assert.Equal(t, "new@example.com", stored.PendingEmail)
+}
+
+func TestWhereLoginName(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+
+	// 外部身份创建的用户名可能包含 @，不能被当作邮箱使用
+	legacy := env.CreateUser(t, "c@example.com")
+	owner := env.CreateUser(t, "carol")
+	require.NoError(t, env.DB.Model(owner).Updates(map[string]interface{}{"email": "carol@example.com", "email_verified": true}).Error)
+
+	assert.EqualError(t, (&EmailService{}).ChangeEmail(owner.ID, "c@example.com"), "邮箱已被使用")
+
+	var found model.User
+	require.NoError(t, whereLoginName(env.DB, "carol").First(&found).Error)
+	assert.Equal(t, owner.ID, found.ID)
+	require.NoError(t, whereLoginName(env.DB, "carol@example.com").First(&found).Error)
+	assert.Equal(t, owner.ID, found.ID)
+
+	// 含 @ 的登录名只按邮箱匹配
+	assert.Error(t, whereLoginName(env.DB, legacy.Username).First(&model.User{}).Error)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/config"
+	"users-by-go-example/internal/model"
+)
+
+const (
+	loginFailKeyPrefix  = "login_fail:"       // 失败计数，窗口期内累计
+	loginLockKeyPrefix  = "login_lock:"       // 锁定标记，过期即自动解锁
+	loginLevelKeyPrefix = "login_lock_level:" // 已锁定次数，用于计算指数退避
+)
+
+// ErrLoginLocked 锁定提示不区分账号是否存在，未注册的用户名同样会被计数和锁定
+var ErrLoginLocked = errors.New("登录失败次数过多，请稍后重试")
+
+// LoginGuardService 登录防暴力破解：按账号和客户端 IP 分别统计失败次数，超过阈值后临时锁定
+type LoginGuardService struct{}
+
+// accountSubject 计数对象：已存在的用户按 ID 计数，用户名和邮箱登录共用同一计数；不存在的按提交的账号计数
+func accountSubject(userId int64, username string) string {
+	if userId > 0 {
+		return fmt.Sprintf("account:%d", userId)
+	}
+	return "name:" + strings.ToLower(username)
+}
+
+// Check 账号或 IP 处于锁定期时返回 ErrLoginLocked
+func (s *LoginGuardService) Check(account, clientIP string) error {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	n, err := rdb.Exists(ctx, loginLockKeyPrefix+account, loginLockKeyPrefix+"ip:"+clientIP).Result()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrLoginLocked
+	}
+	return nil
+}
+
+// RecordFailure 记录一次登录失败，达到阈值时锁定账号或 IP
+func (s *LoginGuardService) RecordFailure(account, clientIP string) error {
+	conf := application.GetConfig().LoginLock
+
+	if err := s.recordFailure(account, conf.MaxFailures, &conf); err != nil {
+		return err
+	}
+	return s.recordFailure("ip:"+clientIP, conf.IPMaxFailures, &conf)
+}
+
+// RecordSuccess 登录成功后清空账号的失败计数和退避等级；IP 计数不清空，防止用自己的账号刷新计数
+func (s *LoginGuardService) RecordSuccess(account string) error {
+	return application.GetRedis().Del(context.Background(), loginFailKeyPrefix+account, loginLevelKeyPrefix+account).Err()
+}
+
+// Unlock 管理员解锁账号
+func (s *LoginGuardService) Unlock(userId int64) error {
+	db := application.GetDB()
+
+	var user model.User
+	if err := db.Select("id").Where("id = ? AND `delete` = 0", userId).First(&user).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+
+	account := accountSubject(userId, "")
+	return application.GetRedis().Del(context.Background(),
+		loginFailKeyPrefix+account,
+		loginLockKeyPrefix+account,
+		loginLevelKeyPrefix+account,
+	).Err()
+}
+
+func (s *LoginGuardService) recordFailure(subject string, maxFailures int, conf *config.LoginLockConfig) error {
+	if maxFailures <= 0 {
+		return nil
+	}
+
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	failKey := loginFailKeyPrefix + subject
+	count, err := rdb.Incr(ctx, failKey).Result()
+	if err != nil {
+		return err
+	}
+	if count == 1 {
+		rdb.Expire(ctx, failKey, time.Duration(conf.FailureWindow)*time.Minute)
+	}
+	if count < int64(maxFailures) {
+		return nil
+	}
+
+	// 每次锁定时长翻倍，直到上限；退避等级在一段时间内没有再被锁定后自动清零
+	levelKey := loginLevelKeyPrefix + subject
+	level, err := rdb.Incr(ctx, levelKey).Result()
+	if err != nil {
+		return err
+	}
+	rdb.Expire(ctx, levelKey, 24*time.Hour)
+
+	lockTTL := lockDuration(level, conf)
+	pipe := rdb.TxPipeline()
+	pipe.Set(ctx, loginLockKeyPrefix+subject, level, lockTTL)
+	pipe.Del(ctx, failKey)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// lockDuration 第 level 次锁定的时长：base * 2^(level-1)，不超过 max
+func lockDuration(level int64, conf *config.LoginLockConfig) time.Duration {
+	base := time.Duration(conf.LockDuration) * time.Minute
+	max := time.Duration(conf.MaxLockDuration) * time.Minute
+	if base <= 0 {
+		base = time.Minute
+	}
+
+	d := base
+	for i := int64(1); i < level; i++ {
+		d *= 2
+		if max > 0 && d >= max {
+			return max
+		}
+	}
+	if max > 0 && d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	totpEnrollKeyPrefix = "totp_enroll:"  // 待确认的 TOTP 密钥，key 为用户 ID
+	totpUsedKeyPrefix   = "totp_used:"    // 已使用过的时间步，防止验证码重放
+	mfaPendingKeyPrefix = "mfa_pending:"  // 两步验证待完成令牌，key 为令牌摘要
+	mfaAttemptKeyPrefix = "mfa_attempts:" // 两步验证失败次数，key 为用户 ID
+
+	recoveryCodeCount    = 10 // 每次生成的恢复码数量
+	mfaMaxAttempts       = 5  // 窗口内允许的验证码失败次数，登录和关闭两步验证共用
+	mfaAttemptWindow     = 15 * time.Minute
+	totpEnrollTTL        = 10 * time.Minute
+	defaultMfaPendingTTL = 5 * time.Minute
+)
+
+var (
+	ErrMfaTokenInvalid    = errors.New("两步验证已过期，请重新登录")
+	ErrMfaCodeInvalid     = errors.New("验证码错误")
+	ErrMfaTooManyAttempts = errors.New("验证码错误次数过多，请稍后再试")
+)
+
+// MfaService 两步验证服务
+type MfaService struct{}
+
+// Enroll 生成新的 TOTP 密钥，确认前只暂存在 Redis 中
+func (s *MfaService) Enroll(userId int64) (*model.TotpEnrollResponse, error) {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	user, err := s.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TotpEnabled {
+		return nil, errors.New("已开启两步验证")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := rdb.Set(ctx, fmt.Sprintf("%s%d", totpEnrollKeyPrefix, userId), secret, totpEnrollTTL).Err(); err != nil {
+		return nil, err
+	}
+
+	account := user.Username
+	if user.Email != "" {
+		account = user.Email
+	}
+	return &model.TotpEnrollResponse{
+		Secret: secret,
+		URI:    utils.TOTPProvisioningURI(application.GetConfig().Mfa.Issuer, account, secret),
+	}, nil
+}
+
+// Confirm 校验验证器 App 生成的验证码，开启两步验证并返回恢复码
+func (s *MfaService) Confirm(userId int64, code string) ([]string, error) {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	key := fmt.Sprintf("%s%d", totpEnrollKeyPrefix, userId)
+	secret, err := rdb.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errors.New("请先获取绑定密钥")
+		}
+		return nil, err
+	}
+	if _, ok := utils.ValidateTOTP(secret, code, time.Now()); !ok {
+		return nil, ErrMfaCodeInvalid
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.User{}).Where("id = ? AND `delete` = 0", userId).Updates(map[string]interface{}{
+			"totp_secret":  secret,
+			"totp_enabled": true,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("用户不存在")
+		}
+		return replaceRecoveryCodes(tx, userId, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rdb.Del(ctx, key)
+	return codes, nil
+}
+
+// Disable 关闭两步验证，需要提供有效的验证码或恢复码
+func (s *MfaService) Disable(userId int64, req *model.TotpDisableRequest) error {
+	db := application.GetDB()
+
+	user, err := s.getUser(userId)
+	if err != nil {
+		return err
+	}
+	if !user.TotpEnabled {
+		return errors.New("未开启两步验证")
+	}
+	if err := s.verifyCodeLimited(user, req.Code, req.RecoveryCode); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+			"totp_secret":  nil,
+			"totp_enabled": false,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error
+	})
+}
+
+// CreatePendingLogin 密码校验通过后签发两步验证待完成令牌
+func (s *MfaService) CreatePendingLogin(userId int64) (string, error) {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	ttl := time.Duration(application.GetConfig().Mfa.PendingTTL) * time.Minute
+	if ttl <= 0 {
+		ttl = defaultMfaPendingTTL
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := rdb.Set(ctx, mfaPendingKeyPrefix+utils.HashToken(token), userId, ttl).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// VerifyLogin 使用待完成令牌和验证码（或恢复码）完成登录
+func (s *MfaService) VerifyLogin(req *model.MfaLoginRequest, client *model.ClientInfo) (*model.TokenResponse, error) {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	hash := utils.HashToken(req.MfaToken)
+	userId, err := rdb.Get(ctx, mfaPendingKeyPrefix+hash).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrMfaTokenInvalid
+		}
+		return nil, err
+	}
+
+	user, err := s.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证码错误与密码错误共用登录锁定计数
+	guard := &LoginGuardService{}
+	account := accountSubject(user.ID, "")
+	if err := guard.Check(account, client.IP); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyCodeLimited(user, req.Code, req.RecoveryCode); err != nil {
+		if errors.Is(err, ErrMfaCodeInvalid) || errors.Is(err, ErrMfaTooManyAttempts) {
+			if err := guard.RecordFailure(account, client.IP); err != nil {
+				return nil, err
+			}
+		}
+		// 失败次数过多时作废待完成令牌，必须重新输入密码
+		if errors.Is(err, ErrMfaTooManyAttempts) {
+			rdb.Del(ctx, mfaPendingKeyPrefix+hash)
+		}
+		return nil, err
+	}
+
+	// 待完成令牌只能使用一次
+	if deleted, err := rdb.Del(ctx, mfaPendingKeyPrefix+hash).Result(); err != nil {
+		return nil, err
+	} else if deleted == 0 {
+		return nil, ErrMfaTokenInvalid
+	}
+	if err := guard.RecordSuccess(account); err != nil {
+		return nil, err
+	}
+
+	return (&TokenService{}).IssueTokenPair(user.ID, user.Username, client)
+}
+
+// verifyCodeLimited 按用户限制验证码的失败次数后校验，先计数再校验，并发请求也不能超出限制
+func (s *MfaService) verifyCodeLimited(user *model.User, code, recoveryCode string) error {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	key := fmt.Sprintf("%s%d", mfaAttemptKeyPrefix, user.ID)
+	attempts, err := rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if attempts == 1 {
+		rdb.Expire(ctx, key, mfaAttemptWindow)
+	}
+	if attempts > mfaMaxAttempts {
+		return ErrMfaTooManyAttempts
+	}
+
+	if err := s.verifyCode(user, code, recoveryCode); err != nil {
+		if !errors.Is(err, ErrMfaCodeInvalid) {
+			// 参数错误等非验证码错误不计入失败次数
+			rdb.Decr(ctx, key)
+			return err
+		}
+		if attempts == mfaMaxAttempts {
+			return ErrMfaTooManyAttempts
+		}
+		return err
+	}
+	rdb.Del(ctx, key)
+	return nil
+}
+
+// verifyCode 校验 TOTP 验证码或恢复码，二者至少提供一个
+func (s *MfaService) verifyCode(user *model.User, code, recoveryCode string) error {
+	if code != "" {
+		step, ok := utils.ValidateTOTP(user.TotpSecret, code, time.Now())
+		if !ok {
+			return ErrMfaCodeInvalid
+		}
+		// 同一时间步的验证码只能使用一次
+		key := fmt.Sprintf("%s%d:%d", totpUsedKeyPrefix, user.ID, step)
+		ok, err := application.GetRedis().SetNX(context.Background(), key, 1, 3*time.Minute).Result()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrMfaCodeInvalid
+		}
+		return nil
+	}
+
+	if recoveryCode != "" {
+		return useRecoveryCode(user.ID, recoveryCode)
+	}
+
+	return errors.New("请提供验证码或恢复码")
+}
+
+func (s *MfaService) getUser(userId int64) (*model.User, error) {
+	var user model.User
+	if err := application.GetDB().Where("id = ? AND `delete` = 0", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// generateRecoveryCodes 生成恢复码明文及其摘要，明文格式为 xxxxx-xxxxx
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	buf := make([]byte, 5)
+	for i := 0; i < recoveryCodeCount; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode 恢复码不区分大小写和连字符
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return utils.HashToken(code)
+}
+
+// replaceRecoveryCodes 用新的恢复码替换用户原有的全部恢复码
+func replaceRecoveryCodes(tx *gorm.DB, userId int64, hashes []string) error {
+	if err := tx.Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]*model.RecoveryCode, 0, len(hashes))
+	for _, hash := range hashes {
+		codes = append(codes, &model.RecoveryCode{UserId: userId, CodeHash: hash})
+	}
+	return tx.Create(&codes).Error
+}
+
+// useRecoveryCode 核销一个未使用的恢复码，条件更新保证并发下只能使用一次
+func useRecoveryCode(userId int64, code string) error {
+	result := application.GetDB().Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_time IS NULL", userId, hashRecoveryCode(code)).
+		Update("used_time", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMfaCodeInvalid
+	}
+	return nil
+}
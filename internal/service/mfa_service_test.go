@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+	"users-by-go-example/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// enableTotp 直接为用户开启两步验证，返回密钥
+func enableTotp(t *testing.T, env *testenv.Env, user *model.User) string {
+	t.Helper()
+	secret, err := utils.GenerateTOTPSecret()
+	require.NoError(t, err)
+	require.NoError(t, env.DB.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": true}).Error)
+	return secret
+}
+
+func TestMfaDisable_AttemptLimit(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	user := env.CreateUser(t, "alice")
+	secret := enableTotp(t, env, user)
+	s := &MfaService{}
+
+	// 缺少验证码不计入失败次数
+	assert.NotErrorIs(t, s.Disable(user.ID, &model.TotpDisableRequest{}), ErrMfaCodeInvalid)
+
+	for i := 1; i < mfaMaxAttempts; i++ {
+		assert.ErrorIs(t, s.Disable(user.ID, &model.TotpDisableRequest{RecoveryCode: "00000-00000"}), ErrMfaCodeInvalid)
+	}
+	assert.ErrorIs(t, s.Disable(user.ID, &model.TotpDisableRequest{RecoveryCode: "00000-00000"}), ErrMfaTooManyAttempts)
+
+	// 达到上限后正确的验证码也被拒绝，登录的第二步共用同一计数
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.Disable(user.ID, &model.TotpDisableRequest{Code: code}), ErrMfaTooManyAttempts)
+
+	token, err := s.CreatePendingLogin(user.ID)
+	require.NoError(t, err)
+	_, err = s.VerifyLogin(&model.MfaLoginRequest{MfaToken: token, Code: code}, &model.ClientInfo{})
+	assert.ErrorIs(t, err, ErrMfaTooManyAttempts)
+	_, err = s.VerifyLogin(&model.MfaLoginRequest{MfaToken: token, Code: code}, &model.ClientInfo{})
+	assert.ErrorIs(t, err, ErrMfaTokenInvalid)
+
+	// 窗口过期后恢复
+	env.Redis.FastForward(mfaAttemptWindow)
+	require.NoError(t, s.Disable(user.ID, &model.TotpDisableRequest{Code: code}))
+
+	var stored model.User
+	require.NoError(t, env.DB.First(&stored, user.ID).Error)
+	assert.False(t, stored.TotpEnabled)
+}
+
+func TestMfaVerifyLogin_LoginGuard(t *testing.T) {
+	conf := testenv.Config(t)
+	conf.LoginLock.MaxFailures = 3
+	env := testenv.Setup(t, conf)
+	user := env.CreateUser(t, "alice")
+	secret := enableTotp(t, env, user)
+	s := &MfaService{}
+	client := &model.ClientInfo{IP: "192.0.2.1"}
+
+	token, err := s.CreatePendingLogin(user.ID)
+	require.NoError(t, err)
+	for i := 0; i < conf.LoginLock.MaxFailures; i++ {
+		_, err = s.VerifyLogin(&model.MfaLoginRequest{MfaToken: token, RecoveryCode: "00000-00000"}, client)
+		assert.ErrorIs(t, err, ErrMfaCodeInvalid)
+	}
+
+	// 验证码错误计入账号的登录锁定，锁定期间正确的验证码也无法登录
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	_, err = s.VerifyLogin(&model.MfaLoginRequest{MfaToken: token, Code: code}, client)
+	assert.ErrorIs(t, err, ErrLoginLocked)
+}
@@ -0,0 +1,569 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/oidc"
+	"users-by-go-example/internal/permit"
+	"users-by-go-example/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	oauthCodeKeyPrefix         = "oauth_code:"          // 授权码，key 为授权码摘要
+	oauthTokenKeyPrefix        = "oauth_token:"         // 访问令牌，key 为令牌摘要
+	oauthClientTokensKeyPrefix = "oauth_client_tokens:" // 客户端已签发的访问令牌摘要集合，删除客户端时吊销
+
+	defaultOAuthCodeTTL  = 5 * time.Minute
+	defaultOAuthTokenTTL = time.Hour
+)
+
+// OIDC 标准 scope，其余 scope 均视为权限标识
+var standardScopes = []string{"openid", "profile", "email"}
+
+// OAuthError OAuth2 标准错误（RFC 6749 5.2）
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, format string, args ...any) *OAuthError {
+	return &OAuthError{Code: code, Description: fmt.Sprintf(format, args...)}
+}
+
+// oauthCodeRecord 授权码在 Redis 中的存储结构
+type oauthCodeRecord struct {
+	ClientID      string   `json:"clientId"`
+	UserID        int64    `json:"userId"`
+	RedirectURI   string   `json:"redirectUri"`
+	Scopes        []string `json:"scopes"`
+	Nonce         string   `json:"nonce"`
+	CodeChallenge string   `json:"codeChallenge"`
+	AuthTime      int64    `json:"authTime"`
+}
+
+// oauthTokenRecord 访问令牌在 Redis 中的存储结构，用于内省和 userinfo
+type oauthTokenRecord struct {
+	ClientID  string   `json:"clientId"`
+	UserID    int64    `json:"userId"` // 客户端凭证模式为 0
+	Username  string   `json:"username"`
+	Scopes    []string `json:"scopes"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+	// Generation 签发时用户的令牌代数，用户修改密码或退出全部设备后令牌失效
+	Generation int64 `json:"gen"`
+}
+
+// OAuthService OAuth2 / OIDC 授权服务器
+type OAuthService struct{}
+
+// CreateClient 注册客户端，机密客户端返回一次明文密钥
+func (s *OAuthService) CreateClient(req *model.CreateOAuthClientRequest) (*model.OAuthClientResponse, error) {
+	db := application.GetDB()
+
+	if slices.Contains(req.GrantTypes, model.GrantTypeAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		return nil, errors.New("授权码模式必须配置回调地址")
+	}
+	if req.Public && slices.Contains(req.GrantTypes, model.GrantTypeClientCredentials) {
+		return nil, errors.New("公开客户端不能使用客户端凭证模式")
+	}
+	for _, value := range append(append([]string{}, req.RedirectURIs...), req.Scopes...) {
+		if strings.Contains(value, ",") {
+			return nil, fmt.Errorf("%s 不能包含逗号", value)
+		}
+	}
+
+	clientId, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	client := &model.OAuthClient{
+		ClientId:     clientId,
+		Name:         req.Name,
+		RedirectURIs: strings.Join(req.RedirectURIs, ","),
+		GrantTypes:   strings.Join(req.GrantTypes, ","),
+		Scopes:       strings.Join(req.Scopes, ","),
+	}
+
+	var secret string
+	if !req.Public {
+		if secret, err = utils.RandomToken(32); err != nil {
+			return nil, err
+		}
+		client.SecretHash = utils.HashToken(secret)
+	}
+
+	if err := db.Create(client).Error; err != nil {
+		return nil, err
+	}
+
+	resp := client.ToResponse()
+	resp.ClientSecret = secret
+	return resp, nil
+}
+
+// ListClients 获取全部客户端
+func (s *OAuthService) ListClients() ([]*model.OAuthClientResponse, error) {
+	db := application.GetDB()
+
+	var clients []*model.OAuthClient
+	if err := db.Order("id").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	responses := make([]*model.OAuthClientResponse, 0, len(clients))
+	for _, client := range clients {
+		responses = append(responses, client.ToResponse())
+	}
+	return responses, nil
+}
+
+// DeleteClient 删除客户端，并吊销该客户端已签发的全部访问令牌
+func (s *OAuthService) DeleteClient(id int64) error {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	var client model.OAuthClient
+	if err := db.Where("id = ?", id).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("客户端不存在")
+		}
+		return err
+	}
+	if err := db.Delete(&client).Error; err != nil {
+		return err
+	}
+
+	setKey := oauthClientTokensKeyPrefix + client.ClientId
+	hashes, err := rdb.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{setKey}
+	for _, hash := range hashes {
+		keys = append(keys, oauthTokenKeyPrefix+hash)
+	}
+	return rdb.Del(ctx, keys...).Err()
+}
+
+// Authorize 当前用户同意授权后签发授权码，返回携带授权码的回调地址
+func (s *OAuthService) Authorize(userId int64, req *model.OAuthAuthorizeRequest) (*model.OAuthAuthorizeResponse, error) {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	client, err := s.getClient(req.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	// 回调地址校验失败时不能跳转，直接返回错误
+	if !slices.Contains(client.RedirectURIList(), req.RedirectURI) {
+		return nil, oauthError("invalid_request", "redirect_uri 未登记")
+	}
+	if !slices.Contains(client.GrantTypeList(), model.GrantTypeAuthorizationCode) {
+		return nil, oauthError("unauthorized_client", "客户端不允许使用授权码模式")
+	}
+
+	scopes, err := s.grantUserScopes(client, userId, strings.Fields(req.Scope))
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(&oauthCodeRecord{
+		ClientID:      client.ClientId,
+		UserID:        userId,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := rdb.Set(ctx, oauthCodeKeyPrefix+utils.HashToken(code), data, s.codeTTL()).Err(); err != nil {
+		return nil, err
+	}
+
+	redirect, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		return nil, oauthError("invalid_request", "redirect_uri 格式错误")
+	}
+	query := redirect.Query()
+	query.Set("code", code)
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	redirect.RawQuery = query.Encode()
+	return &model.OAuthAuthorizeResponse{RedirectURL: redirect.String()}, nil
+}
+
+// AuthenticateClient 校验客户端身份；公开客户端只校验 client_id
+func (s *OAuthService) AuthenticateClient(clientId, clientSecret string) (*model.OAuthClient, error) {
+	client, err := s.getClient(clientId)
+	if err != nil {
+		return nil, oauthError("invalid_client", "客户端认证失败")
+	}
+	if client.Public() {
+		if clientSecret != "" {
+			return nil, oauthError("invalid_client", "客户端认证失败")
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, oauthError("invalid_client", "客户端认证失败")
+	}
+	return client, nil
+}
+
+// ExchangeCode 授权码模式：校验授权码、回调地址和 PKCE code_verifier 后签发访问令牌，申请了 openid 时同时签发 ID Token
+func (s *OAuthService) ExchangeCode(client *model.OAuthClient, code, redirectURI, codeVerifier string) (*model.OAuthTokenResponse, error) {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	if !slices.Contains(client.GrantTypeList(), model.GrantTypeAuthorizationCode) {
+		return nil, oauthError("unauthorized_client", "客户端不允许使用授权码模式")
+	}
+
+	// 授权码只能使用一次
+	key := oauthCodeKeyPrefix + utils.HashToken(code)
+	var get *redis.StringCmd
+	if _, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	}); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	data, err := get.Bytes()
+	if err != nil {
+		return nil, oauthError("invalid_grant", "授权码无效或已过期")
+	}
+	var record oauthCodeRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, oauthError("invalid_grant", "授权码无效或已过期")
+	}
+
+	if record.ClientID != client.ClientId || record.RedirectURI != redirectURI {
+		return nil, oauthError("invalid_grant", "授权码与客户端或回调地址不匹配")
+	}
+	if codeVerifier == "" || subtle.ConstantTimeCompare([]byte(oidc.CodeChallenge(codeVerifier)), []byte(record.CodeChallenge)) != 1 {
+		return nil, oauthError("invalid_grant", "code_verifier 校验失败")
+	}
+
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", record.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, oauthError("invalid_grant", "用户不存在")
+		}
+		return nil, err
+	}
+	generation, err := (&TokenService{}).currentGeneration(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.issueToken(&oauthTokenRecord{
+		ClientID:   client.ClientId,
+		UserID:     user.ID,
+		Username:   user.Username,
+		Scopes:     record.Scopes,
+		Generation: generation,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(record.Scopes, "openid") {
+		if resp.IDToken, err = s.signIDToken(client, &user, &record); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// ClientCredentials 客户端凭证模式：客户端以自身身份获取令牌，scope 只能是登记时允许的权限标识
+func (s *OAuthService) ClientCredentials(client *model.OAuthClient, scope string) (*model.OAuthTokenResponse, error) {
+	if client.Public() || !slices.Contains(client.GrantTypeList(), model.GrantTypeClientCredentials) {
+		return nil, oauthError("unauthorized_client", "客户端不允许使用客户端凭证模式")
+	}
+
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		requested = client.ScopeList()
+	}
+	scopes := make([]string, 0, len(requested))
+	for _, item := range requested {
+		if slices.Contains(standardScopes, item) {
+			return nil, oauthError("invalid_scope", "客户端凭证模式不支持 %s", item)
+		}
+		if !permit.MatchAny(client.ScopeList(), item) {
+			return nil, oauthError("invalid_scope", "客户端不允许申请 %s", item)
+		}
+		scopes = append(scopes, item)
+	}
+
+	return s.issueToken(&oauthTokenRecord{ClientID: client.ClientId, Scopes: scopes})
+}
+
+// Introspect 令牌内省，无效或已过期的令牌只返回 active=false
+func (s *OAuthService) Introspect(token string) (*model.OAuthIntrospection, error) {
+	record, err := s.lookupToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return &model.OAuthIntrospection{Active: false}, nil
+	}
+
+	result := &model.OAuthIntrospection{
+		Active:    true,
+		Scope:     strings.Join(record.Scopes, " "),
+		ClientId:  record.ClientID,
+		Username:  record.Username,
+		Subject:   record.ClientID,
+		TokenType: "Bearer",
+		ExpiresAt: record.ExpiresAt,
+		IssuedAt:  record.IssuedAt,
+	}
+	if record.UserID > 0 {
+		result.Subject = strconv.FormatInt(record.UserID, 10)
+	}
+	return result, nil
+}
+
+// UserInfo OIDC userinfo：按令牌的 scope 返回用户信息，permits 为令牌获得的权限标识
+func (s *OAuthService) UserInfo(token string) (map[string]any, error) {
+	db := application.GetDB()
+
+	record, err := s.lookupToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.UserID == 0 || !slices.Contains(record.Scopes, "openid") {
+		return nil, oauthError("invalid_token", "访问令牌无效、已过期或缺少 openid scope")
+	}
+
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", record.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, oauthError("invalid_token", "用户不存在")
+		}
+		return nil, err
+	}
+
+	info := userClaims(&user, record.Scopes)
+	info["sub"] = strconv.FormatInt(user.ID, 10)
+
+	permits := make([]string, 0, len(record.Scopes))
+	for _, scope := range record.Scopes {
+		if !slices.Contains(standardScopes, scope) {
+			permits = append(permits, scope)
+		}
+	}
+	info["permits"] = permits
+	return info, nil
+}
+
+// grantUserScopes 计算授予第三方应用的 scope：必须是客户端允许申请的；权限标识类 scope 只授予用户实际拥有的
+func (s *OAuthService) grantUserScopes(client *model.OAuthClient, userId int64, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, oauthError("invalid_scope", "scope 不能为空")
+	}
+
+	var userPermits []string
+	loaded := false
+	granted := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		if slices.Contains(standardScopes, scope) {
+			if !slices.Contains(client.ScopeList(), scope) {
+				return nil, oauthError("invalid_scope", "客户端不允许申请 %s", scope)
+			}
+			// 共享密钥签发的 ID Token 第三方应用无法验签，必须配置非对称密钥
+			if scope == "openid" && utils.SigningAlg() == "" {
+				return nil, oauthError("invalid_scope", "未配置非对称签名密钥，不支持 openid")
+			}
+			granted = append(granted, scope)
+			continue
+		}
+
+		if !permit.MatchAny(client.ScopeList(), scope) {
+			return nil, oauthError("invalid_scope", "客户端不允许申请 %s", scope)
+		}
+		if !loaded {
+			permits, _, err := (&PermissionService{}).GetCachedUserPermits(userId)
+			if err != nil {
+				return nil, err
+			}
+			userPermits, loaded = permits, true
+		}
+		// 用户不具备的权限不授予，也不报错（RFC 6749 3.3 允许授权服务器缩小 scope）
+		if permit.MatchAny(userPermits, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return granted, nil
+}
+
+// issueToken 签发不透明访问令牌，令牌内容保存在 Redis 中
+func (s *OAuthService) issueToken(record *oauthTokenRecord) (*model.OAuthTokenResponse, error) {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	ttl := time.Duration(application.GetConfig().OAuth.AccessTokenTTL) * time.Minute
+	if ttl <= 0 {
+		ttl = defaultOAuthTokenTTL
+	}
+	now := time.Now()
+	record.IssuedAt = now.Unix()
+	record.ExpiresAt = now.Add(ttl).Unix()
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	hash := utils.HashToken(token)
+	setKey := oauthClientTokensKeyPrefix + record.ClientID
+	pipe := rdb.TxPipeline()
+	pipe.Set(ctx, oauthTokenKeyPrefix+hash, data, ttl)
+	pipe.SAdd(ctx, setKey, hash)
+	pipe.Expire(ctx, setKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return &model.OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl.Seconds()),
+		Scope:       strings.Join(record.Scopes, " "),
+	}, nil
+}
+
+// lookupToken 查找访问令牌，不存在或已失效时返回 nil
+// 签发后客户端被删除、用户被删除或用户的令牌代数已递增（修改密码、退出全部设备等），令牌均视为失效
+func (s *OAuthService) lookupToken(token string) (*oauthTokenRecord, error) {
+	db := application.GetDB()
+	ctx := context.Background()
+
+	if token == "" {
+		return nil, nil
+	}
+	data, err := application.GetRedis().Get(ctx, oauthTokenKeyPrefix+utils.HashToken(token)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var record oauthTokenRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, nil
+	}
+
+	// 与删除客户端并发签发的令牌不在吊销集合中，这里再确认一次客户端仍然存在
+	var count int64
+	if err := db.Model(&model.OAuthClient{}).Where("client_id = ?", record.ClientID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	if record.UserID > 0 {
+		if err := db.Model(&model.User{}).Where("id = ? AND `delete` = 0", record.UserID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, nil
+		}
+		generation, err := (&TokenService{}).currentGeneration(ctx, record.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if record.Generation < generation {
+			return nil, nil
+		}
+	}
+	return &record, nil
+}
+
+// signIDToken 签发 ID Token，使用与访问令牌相同的签名密钥，第三方应用可通过 JWKS 验签
+func (s *OAuthService) signIDToken(client *model.OAuthClient, user *model.User, record *oauthCodeRecord) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       application.GetConfig().OAuth.Issuer,
+		"sub":       strconv.FormatInt(user.ID, 10),
+		"aud":       client.ClientId,
+		"iat":       now.Unix(),
+		"exp":       now.Add(defaultOAuthTokenTTL).Unix(),
+		"auth_time": record.AuthTime,
+	}
+	if record.Nonce != "" {
+		claims["nonce"] = record.Nonce
+	}
+	for k, v := range userClaims(user, record.Scopes) {
+		claims[k] = v
+	}
+	return utils.SignJWT(claims)
+}
+
+// userClaims 按 profile、email scope 返回用户声明
+func userClaims(user *model.User, scopes []string) map[string]any {
+	claims := map[string]any{}
+	if slices.Contains(scopes, "profile") {
+		claims["preferred_username"] = user.Username
+		if user.NikeName != "" {
+			claims["name"] = user.NikeName
+		}
+	}
+	if slices.Contains(scopes, "email") && user.Email != "" {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.Verified
+	}
+	return claims
+}
+
+func (s *OAuthService) getClient(clientId string) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	if err := application.GetDB().Where("client_id = ?", clientId).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, oauthError("invalid_client", "客户端不存在")
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (s *OAuthService) codeTTL() time.Duration {
+	ttl := time.Duration(application.GetConfig().OAuth.CodeTTL) * time.Minute
+	if ttl <= 0 {
+		return defaultOAuthCodeTTL
+	}
+	return ttl
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/oidc"
+	"users-by-go-example/internal/testenv"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRedirectURI  = "https://app.example.com/callback"
+	testCodeVerifier = "test-code-verifier-0123456789-0123456789-0123456789"
+)
+
+// oauthErrorCode 返回 OAuth 错误码，不是 OAuthError 时返回空字符串
+func oauthErrorCode(err error) string {
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		return oauthErr.Code
+	}
+	return ""
+}
+
+func createOAuthClient(t *testing.T, public bool, grantTypes ...string) (*model.OAuthClient, string) {
+	t.Helper()
+	resp, err := (&OAuthService{}).CreateClient(&model.CreateOAuthClientRequest{
+		Name:         "app",
+		RedirectURIs: []string{testRedirectURI},
+		GrantTypes:   grantTypes,
+		Scopes:       []string{"profile", "user:*"},
+		Public:       public,
+	})
+	require.NoError(t, err)
+	return resp.OAuthClient, resp.ClientSecret
+}
+
+// authorizeCode 以用户身份同意授权，返回授权码
+func authorizeCode(t *testing.T, client *model.OAuthClient, userId int64, scope string) string {
+	t.Helper()
+	resp, err := (&OAuthService{}).Authorize(userId, &model.OAuthAuthorizeRequest{
+		ResponseType:        "code",
+		ClientId:            client.ClientId,
+		RedirectURI:         testRedirectURI,
+		Scope:               scope,
+		CodeChallenge:       oidc.CodeChallenge(testCodeVerifier),
+		CodeChallengeMethod: "S256",
+	})
+	require.NoError(t, err)
+	redirect, err := url.Parse(resp.RedirectURL)
+	require.NoError(t, err)
+	return redirect.Query().Get("code")
+}
+
+func TestOAuthAuthorize(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &OAuthService{}
+	client, _ := createOAuthClient(t, false, model.GrantTypeAuthorizationCode)
+	user := env.CreateUser(t, "alice", "user:get")
+
+	authorize := func(redirectURI, scope string) error {
+		_, err := s.Authorize(user.ID, &model.OAuthAuthorizeRequest{
+			ResponseType:        "code",
+			ClientId:            client.ClientId,
+			RedirectURI:         redirectURI,
+			Scope:               scope,
+			CodeChallenge:       oidc.CodeChallenge(testCodeVerifier),
+			CodeChallengeMethod: "S256",
+		})
+		return err
+	}
+
+	// 回调地址必须与登记的完全一致
+	for _, redirectURI := range []string{
+		testRedirectURI + "/evil",
+		testRedirectURI + "?next=https://evil.example.com",
+		"https://APP.example.com/callback",
+		"http://app.example.com/callback",
+	} {
+		assert.Equal(t, "invalid_request", oauthErrorCode(authorize(redirectURI, "profile")), redirectURI)
+	}
+
+	// 客户端不允许的 scope 直接拒绝，用户不具备的权限从结果中去掉
+	assert.Equal(t, "invalid_scope", oauthErrorCode(authorize(testRedirectURI, "role:get")))
+	assert.Equal(t, "invalid_scope", oauthErrorCode(authorize(testRedirectURI, "email")))
+
+	code := authorizeCode(t, client, user.ID, "profile user:get user:delete")
+	resp, err := s.ExchangeCode(client, code, testRedirectURI, testCodeVerifier)
+	require.NoError(t, err)
+	assert.Equal(t, "profile user:get", resp.Scope)
+}
+
+func TestOAuthExchangeCode(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &OAuthService{}
+	client, _ := createOAuthClient(t, false, model.GrantTypeAuthorizationCode)
+	other, _ := createOAuthClient(t, false, model.GrantTypeAuthorizationCode)
+	user := env.CreateUser(t, "alice")
+
+	// PKCE 校验失败后授权码已作废，不能再用正确的 code_verifier 重试
+	code := authorizeCode(t, client, user.ID, "profile")
+	_, err := s.ExchangeCode(client, code, testRedirectURI, "wrong-verifier")
+	assert.Equal(t, "invalid_grant", oauthErrorCode(err))
+	_, err = s.ExchangeCode(client, code, testRedirectURI, "")
+	assert.Equal(t, "invalid_grant", oauthErrorCode(err))
+	_, err = s.ExchangeCode(client, code, testRedirectURI, testCodeVerifier)
+	assert.Equal(t, "invalid_grant", oauthErrorCode(err))
+
+	// 换取令牌时的回调地址和客户端必须与授权时一致
+	code = authorizeCode(t, client, user.ID, "profile")
+	_, err = s.ExchangeCode(client, code, testRedirectURI+"/", testCodeVerifier)
+	assert.Equal(t, "invalid_grant", oauthErrorCode(err))
+	code = authorizeCode(t, client, user.ID, "profile")
+	_, err = s.ExchangeCode(other, code, testRedirectURI, testCodeVerifier)
+	assert.Equal(t, "invalid_grant", oauthErrorCode(err))
+
+	// 授权码只能使用一次
+	code = authorizeCode(t, client, user.ID, "profile")
+	resp, err := s.ExchangeCode(client, code, testRedirectURI, testCodeVerifier)
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.AccessToken)
+	_, err = s.ExchangeCode(client, code, testRedirectURI, testCodeVerifier)
+	assert.Equal(t, "invalid_grant", oauthErrorCode(err))
+}
+
+func TestOAuthAuthenticateClient(t *testing.T) {
+	testenv.Setup(t, testenv.Config(t))
+	s := &OAuthService{}
+
+	confidential, secret := createOAuthClient(t, false, model.GrantTypeAuthorizationCode, model.GrantTypeClientCredentials)
+	_, err := s.AuthenticateClient(confidential.ClientId, secret)
+	assert.NoError(t, err)
+	for _, wrong := range []string{"", "wrong", secret + "x"} {
+		_, err = s.AuthenticateClient(confidential.ClientId, wrong)
+		assert.Equal(t, "invalid_client", oauthErrorCode(err))
+	}
+
+	// 公开客户端没有密钥，携带密钥反而拒绝，且不能使用客户端凭证模式
+	public, publicSecret := createOAuthClient(t, true, model.GrantTypeAuthorizationCode)
+	assert.Empty(t, publicSecret)
+	client, err := s.AuthenticateClient(public.ClientId, "")
+	require.NoError(t, err)
+	_, err = s.AuthenticateClient(public.ClientId, "any")
+	assert.Equal(t, "invalid_client", oauthErrorCode(err))
+	_, err = s.ClientCredentials(client, "user:get")
+	assert.Equal(t, "unauthorized_client", oauthErrorCode(err))
+
+	_, err = s.AuthenticateClient("missing", "")
+	assert.Equal(t, "invalid_client", oauthErrorCode(err))
+
+	// 客户端凭证模式的 scope 不能超出登记范围
+	client, err = s.AuthenticateClient(confidential.ClientId, secret)
+	require.NoError(t, err)
+	_, err = s.ClientCredentials(client, "role:get")
+	assert.Equal(t, "invalid_scope", oauthErrorCode(err))
+	resp, err := s.ClientCredentials(client, "user:get")
+	require.NoError(t, err)
+	assert.Equal(t, "user:get", resp.Scope)
+}
+
+func TestOAuthTokenRevocation(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &OAuthService{}
+	client, secret := createOAuthClient(t, false, model.GrantTypeAuthorizationCode, model.GrantTypeClientCredentials)
+	user := env.CreateUser(t, "alice")
+
+	issue := func() string {
+		resp, err := s.ExchangeCode(client, authorizeCode(t, client, user.ID, "profile"), testRedirectURI, testCodeVerifier)
+		require.NoError(t, err)
+		return resp.AccessToken
+	}
+	active := func(token string) bool {
+		result, err := s.Introspect(token)
+		require.NoError(t, err)
+		return result.Active
+	}
+
+	// 吊销用户的全部会话后，已授权给第三方应用的令牌同样失效
+	token := issue()
+	assert.True(t, active(token))
+	require.NoError(t, (&TokenService{}).RevokeAllSessions(user.ID))
+	assert.False(t, active(token))
+	token = issue()
+	assert.True(t, active(token))
+
+	// 用户被删除后令牌失效
+	require.NoError(t, env.DB.Model(user).Update("delete", 1).Error)
+	assert.False(t, active(token))
+	require.NoError(t, env.DB.Model(user).Update("delete", 0).Error)
+	assert.True(t, active(token))
+
+	// 删除客户端会吊销其签发的全部令牌
+	authenticated, err := s.AuthenticateClient(client.ClientId, secret)
+	require.NoError(t, err)
+	resp, err := s.ClientCredentials(authenticated, "user:get")
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteClient(client.ID))
+	assert.False(t, active(token))
+	assert.False(t, active(resp.AccessToken))
+	for _, key := range env.Redis.Keys() {
+		assert.NotContains(t, key, "oauth_", key)
+	}
+}
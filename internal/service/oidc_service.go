@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/oidc"
+	"users-by-go-example/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcStateKeyPrefix  = "oidc_state:" // 单点登录进行中的状态，key 为 state
+	defaultOIDCStateTTL = 10 * time.Minute
+)
+
+var (
+	ErrOIDCProviderNotFound = errors.New("未配置该身份提供方")
+	ErrOIDCStateInvalid     = errors.New("登录请求无效或已过期，请重新登录")
+	ErrOIDCUserNotLinked    = errors.New("该账号尚未绑定本系统用户，请联系管理员")
+)
+
+// oidcState 发起登录时保存的状态，回调时校验
+type oidcState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+	BindingHash  string `json:"bindingHash"` // 浏览器绑定值的摘要，明文只返回给发起登录的前端
+}
+
+// OIDCService 外部身份提供方单点登录服务
+type OIDCService struct{}
+
+// Start 发起单点登录：生成 state、nonce、PKCE code_verifier 和浏览器绑定值，返回身份提供方的授权地址
+// state 会出现在授权地址和回调地址中，单凭 state 不能完成登录，回调时还必须提交只返回给发起方的绑定值
+func (s *OIDCService) Start(provider string) (*model.OIDCLoginResponse, error) {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	client := application.GetOIDCClient(provider)
+	if client == nil {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	state, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := utils.RandomToken(48)
+	if err != nil {
+		return nil, err
+	}
+	binding, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(&oidcState{
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		BindingHash:  utils.HashToken(binding),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(application.GetConfig().OIDC.StateTTL) * time.Minute
+	if ttl <= 0 {
+		ttl = defaultOIDCStateTTL
+	}
+	if err := rdb.Set(ctx, oidcStateKeyPrefix+utils.HashToken(state), data, ttl).Err(); err != nil {
+		return nil, err
+	}
+
+	authURL, err := client.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		return nil, err
+	}
+	return &model.OIDCLoginResponse{AuthURL: authURL, Binding: binding}, nil
+}
+
+// Callback 处理身份提供方回调：用授权码换取并校验 ID Token，找到或创建绑定的本地用户后完成登录
+func (s *OIDCService) Callback(req *model.OIDCCallbackRequest, client *model.ClientInfo) (*model.LoginResponse, error) {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	// state 只能使用一次
+	key := oidcStateKeyPrefix + utils.HashToken(req.State)
+	var get *redis.StringCmd
+	if _, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	}); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	data, err := get.Bytes()
+	if err != nil {
+		return nil, ErrOIDCStateInvalid
+	}
+	var state oidcState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, ErrOIDCStateInvalid
+	}
+	// 回调必须来自发起登录的浏览器，否则攻击者可以诱导受害者提交攻击者自己的 code 和 state
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(req.Binding)), []byte(state.BindingHash)) != 1 {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	oidcClient := application.GetOIDCClient(state.Provider)
+	if oidcClient == nil {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	token, err := oidcClient.Exchange(ctx, req.Code, state.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := oidcClient.VerifyIDToken(ctx, token.IDToken, state.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.findOrProvisionUser(oidcClient, claims)
+	if err != nil {
+		return nil, err
+	}
+	return completeLogin(user, client)
+}
+
+// findOrProvisionUser 按 (provider, sub) 查找绑定的用户，未绑定且开启自动创建时创建本地用户
+func (s *OIDCService) findOrProvisionUser(client *oidc.Client, claims *oidc.IDTokenClaims) (*model.User, error) {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	ctx := context.Background()
+	conf := client.Config()
+
+	lock := utils.NewRedisLock(rdb, fmt.Sprintf("oidc:%s:%s", conf.Name, claims.Subject), 10*time.Second)
+	if err := lock.TryLock(ctx, 3, 100*time.Millisecond); err != nil {
+		if errors.Is(err, utils.ErrLockFailed) {
+			return nil, errors.New("系统繁忙，请稍后重试")
+		}
+		return nil, err
+	}
+	defer lock.Unlock(ctx)
+
+	var identity model.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", conf.Name, claims.Subject).First(&identity).Error
+	if err == nil {
+		var user model.User
+		if err := db.Where("id = ? AND `delete` = 0", identity.UserId).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("用户不存在")
+			}
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if !conf.AutoProvision {
+		return nil, ErrOIDCUserNotLinked
+	}
+
+	// 本地密码随机生成且不返回，自动创建的用户只能通过单点登录或找回密码登录
+	randomPassword, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := utils.HashPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Password: hashedPassword,
+		NikeName: claims.Name,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		username, err := availableUsername(tx, claims.Username(conf.UsernameClaim))
+		if err != nil {
+			return err
+		}
+		user.Username = username
+
+		// 邮箱已被其他用户使用时不自动关联，避免通过外部身份接管已有账号
+		if claims.Email != "" {
+			var count int64
+			if err := tx.Model(&model.User{}).Where("email = ?", claims.Email).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				// 身份提供方未验证的邮箱需要在本系统验证后才生效
+				if claims.EmailVerified {
+					user.Email = claims.Email
+					user.Verified = true
+				} else {
+					user.PendingEmail = claims.Email
+				}
+			}
+		}
+
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.UserIdentity{
+			UserId:   user.ID,
+			Provider: conf.Name,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// availableUsername 以身份提供方返回的用户名为基础生成未被占用的本地用户名
+func availableUsername(tx *gorm.DB, base string) (string, error) {
+	// 本地用户名不能含 @，否则会被按邮箱登录的查找误判为邮箱
+	if i := strings.Index(base, "@"); i >= 0 {
+		base = base[:i]
+	}
+	base = strings.TrimSpace(base)
+	if base == "" {
+		base = "user"
+	}
+	if runes := []rune(base); len(runes) > 40 {
+		base = string(runes[:40])
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := tx.Model(&model.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		suffix, err := utils.RandomToken(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + strings.ToLower(suffix)
+	}
+	return "", errors.New("无法生成可用的用户名，请联系管理员")
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+	"users-by-go-example/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCCallback_Binding(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &OIDCService{}
+
+	// 保存一个发起中的登录状态，provider 未配置，校验通过后才会在查找 provider 时失败
+	saveState := func(state, binding string) {
+		data, err := json.Marshal(&oidcState{Provider: "missing", BindingHash: utils.HashToken(binding)})
+		require.NoError(t, err)
+		require.NoError(t, env.Redis.Set(oidcStateKeyPrefix+utils.HashToken(state), string(data)))
+	}
+
+	// 其他浏览器拿到 state 也无法完成登录，且 state 已被作废
+	saveState("state-1", "binding-1")
+	_, err := s.Callback(&model.OIDCCallbackRequest{Code: "code", State: "state-1", Binding: "other"}, &model.ClientInfo{})
+	assert.ErrorIs(t, err, ErrOIDCStateInvalid)
+	_, err = s.Callback(&model.OIDCCallbackRequest{Code: "code", State: "state-1", Binding: "binding-1"}, &model.ClientInfo{})
+	assert.ErrorIs(t, err, ErrOIDCStateInvalid)
+
+	saveState("state-2", "binding-2")
+	_, err = s.Callback(&model.OIDCCallbackRequest{Code: "code", State: "state-2", Binding: "binding-2"}, &model.ClientInfo{})
+	assert.ErrorIs(t, err, ErrOIDCProviderNotFound)
+}
+
+func TestAvailableUsername(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	env.CreateUser(t, "taken")
+
+	cases := map[string]string{
+		"alice":             "alice",
+		"alice@example.com": "alice",
+		" bob @example.com": "bob",
+		"@corp":             "user",
+		"@":                 "user",
+		"":                  "user",
+	}
+	for input, want := range cases {
+		got, err := availableUsername(env.DB, input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	// 已被占用时追加随机后缀
+	got, err := availableUsername(env.DB, "taken@example.com")
+	require.NoError(t, err)
+	assert.Regexp(t, `^taken_[a-z0-9_-]+$`, got)
+	assert.NotContains(t, got, "@")
+}
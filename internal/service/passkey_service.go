@@ -0,0 +1,438 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/ldapauth"
+	"users-by-go-example/internal/mailer"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/utils"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	passkeyRegisterKeyPrefix = "passkey_register:" // 注册仪式状态，key 为用户 ID
+	passkeyLoginKeyPrefix    = "passkey_login:"    // 登录仪式状态，key 为仪式标识摘要
+
+	maxPasskeysPerUser = 10
+	stepUpMaxAttempts  = 5 // 窗口内允许的身份确认次数
+	stepUpWindow       = 15 * time.Minute
+)
+
+var (
+	ErrPasskeyDisabled       = errors.New("未启用通行密钥")
+	ErrPasskeySessionExpire  = errors.New("操作已过期，请重新开始")
+	ErrPasskeyInvalid        = errors.New("通行密钥校验失败")
+	ErrStepUpTooManyAttempts = errors.New("身份确认次数过多，请稍后再试")
+)
+
+// webauthnUser 适配 webauthn.User，用户句柄为用户 ID 的十进制字符串
+type webauthnUser struct {
+	user        *model.User
+	credentials []webauthn.Credential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatInt(u.user.ID, 10))
+}
+
+func (u *webauthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	if u.user.NikeName != "" {
+		return u.user.NikeName
+	}
+	return u.user.Username
+}
+
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// PasskeyService 通行密钥（WebAuthn）服务：注册、管理和无密码登录
+type PasskeyService struct{}
+
+// BeginRegistration 确认身份后生成注册选项，要求可发现凭据和用户验证（生物识别或 PIN），已注册的凭据不能重复注册
+func (s *PasskeyService) BeginRegistration(userId int64, req *model.PasskeyRegisterRequest) (*model.PasskeyRegisterResponse, error) {
+	wa := application.GetWebAuthn()
+	if wa == nil {
+		return nil, ErrPasskeyDisabled
+	}
+
+	user, _, err := s.loadUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	// 通行密钥可以绕过密码和两步验证登录，只凭访问令牌不能添加，防止被盗用的会话留下长期有效的登录方式
+	if err := s.confirmIdentity(user.user, req); err != nil {
+		return nil, err
+	}
+	if len(user.credentials) >= maxPasskeysPerUser {
+		return nil, fmt.Errorf("最多注册 %d 个通行密钥", maxPasskeysPerUser)
+	}
+
+	options, session, err := wa.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{UserVerification: protocol.VerificationRequired}),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveSession(fmt.Sprintf("%s%d", passkeyRegisterKeyPrefix, userId), session); err != nil {
+		return nil, err
+	}
+	return &model.PasskeyRegisterResponse{Options: options}, nil
+}
+
+// FinishRegistration 校验认证器返回的注册结果并保存凭据
+func (s *PasskeyService) FinishRegistration(userId int64, req *model.PasskeyConfirmRequest) (*model.Passkey, error) {
+	db := application.GetDB()
+	wa := application.GetWebAuthn()
+	if wa == nil {
+		return nil, ErrPasskeyDisabled
+	}
+
+	session, err := s.takeSession(fmt.Sprintf("%s%d", passkeyRegisterKeyPrefix, userId))
+	if err != nil {
+		return nil, err
+	}
+
+	user, _, err := s.loadUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+	credential, err := wa.CreateCredential(user, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	credentialId := base64.RawURLEncoding.EncodeToString(credential.ID)
+	var count int64
+	if err := db.Model(&model.Passkey{}).Where("credential_id = ?", credentialId).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("该通行密钥已注册")
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+	passkey := &model.Passkey{
+		UserId:          userId,
+		Name:            req.Name,
+		CredentialId:    credentialId,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      strings.Join(transports, ","),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err := db.Create(passkey).Error; err != nil {
+		return nil, err
+	}
+
+	// 通知失败不影响注册结果
+	if user.user.Email != "" && user.user.Verified {
+		application.GetMailer().Send(&mailer.Message{
+			To:      user.user.Email,
+			Subject: "新的通行密钥",
+			Body: fmt.Sprintf("您好 %s：\n\n您的账号刚刚添加了通行密钥「%s」。\n\n如果这不是您本人的操作，请立即重置密码，重置密码会同时删除全部通行密钥。",
+				user.user.Username, passkey.Name),
+		})
+	}
+	return passkey, nil
+}
+
+// List 列出用户注册的通行密钥
+func (s *PasskeyService) List(userId int64) ([]*model.Passkey, error) {
+	var passkeys []*model.Passkey
+	if err := application.GetDB().Where("user_id = ?", userId).Order("id").Find(&passkeys).Error; err != nil {
+		return nil, err
+	}
+	return passkeys, nil
+}
+
+// Delete 删除用户自己的通行密钥
+func (s *PasskeyService) Delete(userId, id int64) error {
+	result := application.GetDB().Where("id = ? AND user_id = ?", id, userId).Delete(&model.Passkey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("通行密钥不存在")
+	}
+	return nil
+}
+
+// BeginLogin 生成无用户名登录的选项，由认证器选择本站的可发现凭据
+func (s *PasskeyService) BeginLogin() (*model.PasskeyLoginOptionsResponse, error) {
+	wa := application.GetWebAuthn()
+	if wa == nil {
+		return nil, ErrPasskeyDisabled
+	}
+
+	options, session, err := wa.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, err
+	}
+
+	sessionId, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveSession(passkeyLoginKeyPrefix+utils.HashToken(sessionId), session); err != nil {
+		return nil, err
+	}
+	return &model.PasskeyLoginOptionsResponse{SessionId: sessionId, Options: options}, nil
+}
+
+// FinishLogin 校验认证器返回的断言，通过后签发与密码登录相同的令牌
+// 通行密钥本身包含用户验证，不再要求两步验证；签名计数器回退说明凭据可能被克隆，拒绝登录
+func (s *PasskeyService) FinishLogin(req *model.PasskeyLoginRequest, client *model.ClientInfo) (*model.LoginResponse, error) {
+	db := application.GetDB()
+	wa := application.GetWebAuthn()
+	if wa == nil {
+		return nil, ErrPasskeyDisabled
+	}
+
+	session, err := s.takeSession(passkeyLoginKeyPrefix + utils.HashToken(req.SessionId))
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+
+	var passkeys map[string]*model.Passkey
+	var found *model.User
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userId, err := strconv.ParseInt(string(userHandle), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		user, byId, err := s.loadUser(userId)
+		if err != nil {
+			return nil, err
+		}
+		passkeys, found = byId, user.user
+		return user, nil
+	}
+
+	credential, err := wa.ValidateDiscoverableLogin(handler, *session, parsed)
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+	if credential.Authenticator.CloneWarning {
+		return nil, ErrPasskeyInvalid
+	}
+
+	// 认证器提供计数器时用条件更新保证并发提交同一计数器的断言只有一个成功
+	// 同步通行密钥等认证器的计数器始终为 0，挑战已经只能使用一次，直接更新
+	passkey := passkeys[base64.RawURLEncoding.EncodeToString(credential.ID)]
+	query := db.Model(&model.Passkey{}).Where("id = ?", passkey.ID)
+	if credential.Authenticator.SignCount > 0 {
+		query = query.Where("sign_count = ?", passkey.SignCount)
+	}
+	result := query.Updates(map[string]interface{}{
+		"sign_count":     credential.Authenticator.SignCount,
+		"backup_state":   credential.Flags.BackupState,
+		"last_used_time": time.Now(),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if credential.Authenticator.SignCount > 0 && result.RowsAffected == 0 {
+		return nil, ErrPasskeyInvalid
+	}
+
+	// 与密码登录相同：锁定期内拒绝，邮箱未验证的本地用户不能登录
+	guard := &LoginGuardService{}
+	account := accountSubject(found.ID, "")
+	if err := guard.Check(account, client.IP); err != nil {
+		return nil, err
+	}
+	authenticator := "local"
+	if managed, err := ldapManaged(db, found.ID); err != nil {
+		return nil, err
+	} else if managed {
+		authenticator = ldapProvider
+	}
+	if err := checkLoginAllowed(found, authenticator); err != nil {
+		return nil, err
+	}
+	if err := guard.RecordSuccess(account); err != nil {
+		return nil, err
+	}
+
+	tokens, err := (&TokenService{}).IssueTokenPair(found.ID, found.Username, client)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResponse{TokenResponse: tokens}, nil
+}
+
+// confirmIdentity 再次确认身份：开启两步验证的用户可以提交验证码，否则校验当前密码（目录用户校验目录密码）
+func (s *PasskeyService) confirmIdentity(user *model.User, req *model.PasskeyRegisterRequest) error {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	if req.Code != "" {
+		if !user.TotpEnabled {
+			return errors.New("未开启两步验证")
+		}
+		return (&MfaService{}).verifyCodeLimited(user, req.Code, "")
+	}
+	if req.Password == "" {
+		return errors.New("请输入当前密码或两步验证码")
+	}
+
+	allowed, err := utils.AllowRequest(ctx, rdb, fmt.Sprintf("step_up:user:%d", user.ID), stepUpMaxAttempts, stepUpWindow)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrStepUpTooManyAttempts
+	}
+
+	managed, err := ldapManaged(db, user.ID)
+	if err != nil {
+		return err
+	}
+	if managed {
+		return s.verifyDirectoryPassword(user.ID, req.Password)
+	}
+	if user.Password == "" {
+		return errors.New("账号没有本地密码，请开启两步验证后使用验证码确认身份")
+	}
+	if ok, _, _ := utils.VerifyPassword(user.Password, req.Password); !ok {
+		return errors.New("密码错误")
+	}
+	return nil
+}
+
+// verifyDirectoryPassword 通过 LDAP 校验目录用户的密码
+func (s *PasskeyService) verifyDirectoryPassword(userId int64, password string) error {
+	directory := application.GetLDAPDirectory()
+	if directory == nil {
+		return ErrAuthBackendUnavailable
+	}
+
+	var identity model.UserIdentity
+	if err := application.GetDB().Where("user_id = ? AND provider = ?", userId, ldapProvider).First(&identity).Error; err != nil {
+		return err
+	}
+	if _, err := directory.Authenticate(identity.Subject, password); err != nil {
+		if errors.Is(err, ldapauth.ErrInvalidCredentials) || errors.Is(err, ldapauth.ErrUserNotFound) {
+			return errors.New("密码错误")
+		}
+		return fmt.Errorf("%w: %v", ErrAuthBackendUnavailable, err)
+	}
+	return nil
+}
+
+// loadUser 加载用户及其全部凭据，同时返回凭据 ID 到记录的索引
+func (s *PasskeyService) loadUser(userId int64) (*webauthnUser, map[string]*model.Passkey, error) {
+	db := application.GetDB()
+
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("用户不存在")
+		}
+		return nil, nil, err
+	}
+
+	var passkeys []*model.Passkey
+	if err := db.Where("user_id = ?", userId).Find(&passkeys).Error; err != nil {
+		return nil, nil, err
+	}
+
+	byId := make(map[string]*model.Passkey, len(passkeys))
+	credentials := make([]webauthn.Credential, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		id, err := base64.RawURLEncoding.DecodeString(passkey.CredentialId)
+		if err != nil {
+			continue
+		}
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range strings.Split(passkey.Transports, ",") {
+			if transport != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(transport))
+			}
+		}
+		byId[passkey.CredentialId] = passkey
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				UserPresent:    true,
+				UserVerified:   true,
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		})
+	}
+	return &webauthnUser{user: &user, credentials: credentials}, byId, nil
+}
+
+// saveSession 暂存仪式状态，有效期与仪式超时一致
+func (s *PasskeyService) saveSession(key string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(session.Expires)
+	if session.Expires.IsZero() || ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return application.GetRedis().Set(context.Background(), key, data, ttl).Err()
+}
+
+// takeSession 取出并删除仪式状态，每个挑战只能使用一次
+func (s *PasskeyService) takeSession(key string) (*webauthn.SessionData, error) {
+	data, err := application.GetRedis().GetDel(context.Background(), key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrPasskeySessionExpire
+		}
+		return nil, err
+	}
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, ErrPasskeySessionExpire
+	}
+	return &session, nil
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+	"users-by-go-example/utils"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// 认证器数据中的标志位
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// softAuthenticator 软件实现的 WebAuthn 认证器，使用 ES256 密钥和 none 证明
+type softAuthenticator struct {
+	rpID         string
+	origin       string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, env *testenv.Env) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	credentialID := make([]byte, 32)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+	return &softAuthenticator{
+		rpID:         env.Config.WebAuthn.RPID,
+		origin:       env.Config.WebAuthn.RPOrigins[0],
+		key:          key,
+		credentialID: credentialID,
+	}
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony string, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   challenge.String(),
+		"origin":      a.origin,
+		"crossOrigin": false,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func (a *softAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+// register 响应 navigator.credentials.create()
+func (a *softAuthenticator) register(t *testing.T, options any) json.RawMessage {
+	t.Helper()
+	creation, ok := options.(*protocol.CredentialCreation)
+	require.True(t, ok)
+
+	publicKey, err := cbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(append(attested, a.credentialID...), publicKey...)
+
+	attestation, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(flagUserPresent|flagUserVerified|flagAttestedData, attested),
+	})
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encodeURL(a.clientData(t, "webauthn.create", creation.Response.Challenge)),
+		"attestationObject": encodeURL(attestation),
+	})
+}
+
+// login 响应 navigator.credentials.get()，每次签名前计数器加一
+func (a *softAuthenticator) login(t *testing.T, options any, userId int64) json.RawMessage {
+	t.Helper()
+	a.signCount++
+	return a.assert(t, options, userId)
+}
+
+// assert 使用当前计数器签名，不递增计数器
+func (a *softAuthenticator) assert(t *testing.T, options any, userId int64) json.RawMessage {
+	t.Helper()
+	assertion, ok := options.(*protocol.CredentialAssertion)
+	require.True(t, ok)
+
+	clientData := a.clientData(t, "webauthn.get", assertion.Response.Challenge)
+	authData := a.authData(flagUserPresent|flagUserVerified, nil)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encodeURL(clientData),
+		"authenticatorData": encodeURL(authData),
+		"signature":         encodeURL(signature),
+		"userHandle":        encodeURL([]byte(strconv.FormatInt(userId, 10))),
+	})
+}
+
+func (a *softAuthenticator) credential(t *testing.T, response map[string]string) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"id":       encodeURL(a.credentialID),
+		"rawId":    encodeURL(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func encodeURL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// createPasswordUser 创建设置了本地密码和已验证邮箱的用户
+func createPasswordUser(t *testing.T, env *testenv.Env, username, password string, permits ...string) *model.User {
+	t.Helper()
+	user := env.CreateUser(t, username, permits...)
+	hashed, err := utils.HashPassword(password)
+	require.NoError(t, err)
+	require.NoError(t, env.DB.Model(user).Updates(map[string]interface{}{
+		"password":       hashed,
+		"email":          username + "@example.com",
+		"email_verified": true,
+	}).Error)
+	return user
+}
+
+// registerPasskey 完成一次注册仪式
+func registerPasskey(t *testing.T, env *testenv.Env, user *model.User, password string) *softAuthenticator {
+	t.Helper()
+	s := &PasskeyService{}
+	authenticator := newSoftAuthenticator(t, env)
+	options, err := s.BeginRegistration(user.ID, &model.PasskeyRegisterRequest{Password: password})
+	require.NoError(t, err)
+	_, err = s.FinishRegistration(user.ID, &model.PasskeyConfirmRequest{Name: "laptop", Credential: authenticator.register(t, options.Options)})
+	require.NoError(t, err)
+	return authenticator
+}
+
+func TestPasskeyRegistration(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &PasskeyService{}
+	user := createPasswordUser(t, env, "alice", "s3cret-Passw0rd")
+
+	// 只有访问令牌不能添加通行密钥
+	_, err := s.BeginRegistration(user.ID, &model.PasskeyRegisterRequest{})
+	assert.Error(t, err)
+	_, err = s.BeginRegistration(user.ID, &model.PasskeyRegisterRequest{Password: "wrong"})
+	assert.EqualError(t, err, "密码错误")
+	_, err = s.BeginRegistration(user.ID, &model.PasskeyRegisterRequest{Code: "123456"})
+	assert.EqualError(t, err, "未开启两步验证")
+
+	authenticator := newSoftAuthenticator(t, env)
+	options, err := s.BeginRegistration(user.ID, &model.PasskeyRegisterRequest{Password: "s3cret-Passw0rd"})
+	require.NoError(t, err)
+	credential := authenticator.register(t, options.Options)
+	passkey, err := s.FinishRegistration(user.ID, &model.PasskeyConfirmRequest{Name: "laptop", Credential: credential})
+	require.NoError(t, err)
+	assert.Equal(t, encodeURL(authenticator.credentialID), passkey.CredentialId)
+	assert.Equal(t, "alice@example.com", env.Mailer.Last().To)
+
+	// 注册挑战只能使用一次
+	_, err = s.FinishRegistration(user.ID, &model.PasskeyConfirmRequest{Name: "laptop", Credential: credential})
+	assert.ErrorIs(t, err, ErrPasskeySessionExpire)
+
+	// 开启两步验证的用户可以使用验证码确认身份
+	secret := enableTotp(t, env, user)
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	_, err = s.BeginRegistration(user.ID, &model.PasskeyRegisterRequest{Code: code})
+	assert.NoError(t, err)
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &PasskeyService{}
+	user := createPasswordUser(t, env, "alice", "s3cret-Passw0rd")
+	authenticator := registerPasskey(t, env, user, "s3cret-Passw0rd")
+
+	options, err := s.BeginLogin()
+	require.NoError(t, err)
+	credential := authenticator.login(t, options.Options, user.ID)
+	resp, err := s.FinishLogin(&model.PasskeyLoginRequest{SessionId: options.SessionId, Credential: credential}, &model.ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.Token)
+
+	// 登录挑战只能使用一次，重放同一个断言失败
+	_, err = s.FinishLogin(&model.PasskeyLoginRequest{SessionId: options.SessionId, Credential: credential}, &model.ClientInfo{})
+	assert.ErrorIs(t, err, ErrPasskeySessionExpire)
+
+	var passkey model.Passkey
+	require.NoError(t, env.DB.Where("user_id = ?", user.ID).First(&passkey).Error)
+	assert.Equal(t, uint32(1), passkey.SignCount)
+	assert.NotNil(t, passkey.LastUsedTime)
+
+	// 计数器没有增长说明凭据可能被克隆，拒绝登录
+	options, err = s.BeginLogin()
+	require.NoError(t, err)
+	_, err = s.FinishLogin(&model.PasskeyLoginRequest{SessionId: options.SessionId, Credential: authenticator.assert(t, options.Options, user.ID)}, &model.ClientInfo{})
+	assert.ErrorIs(t, err, ErrPasskeyInvalid)
+
+	// 其他用户的用户句柄不能冒用该凭据
+	other := env.CreateUser(t, "bob")
+	options, err = s.BeginLogin()
+	require.NoError(t, err)
+	_, err = s.FinishLogin(&model.PasskeyLoginRequest{SessionId: options.SessionId, Credential: authenticator.login(t, options.Options, other.ID)}, &model.ClientInfo{})
+	assert.ErrorIs(t, err, ErrPasskeyInvalid)
+}
+
+func TestPasskeyLogin_SignCountRace(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &PasskeyService{}
+	user := createPasswordUser(t, env, "alice", "s3cret-Passw0rd")
+	authenticator := registerPasskey(t, env, user, "s3cret-Passw0rd")
+
+	// 模拟读取凭据之后、更新计数器之前，另一个请求已经用同一计数器的断言登录成功
+	raced := false
+	require.NoError(t, env.DB.Callback().Update().Before("gorm:update").Register("test:concurrent_login", func(db *gorm.DB) {
+		if db.Statement.Table == "user_passkey" && !raced {
+			raced = true
+			require.NoError(t, db.Session(&gorm.Session{NewDB: true}).Exec("UPDATE user_passkey SET sign_count = 1 WHERE user_id = ?", user.ID).Error)
+		}
+	}))
+
+	options, err := s.BeginLogin()
+	require.NoError(t, err)
+	_, err = s.FinishLogin(&model.PasskeyLoginRequest{SessionId: options.SessionId, Credential: authenticator.login(t, options.Options, user.ID)}, &model.ClientInfo{})
+	assert.True(t, raced)
+	assert.ErrorIs(t, err, ErrPasskeyInvalid)
+}
+
+func TestPasskeyLogin_ZeroSignCount(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &PasskeyService{}
+	user := createPasswordUser(t, env, "alice", "s3cret-Passw0rd")
+	authenticator := registerPasskey(t, env, user, "s3cret-Passw0rd")
+
+	// MySQL 只把值发生变化的行计入影响行数，计数器始终为 0 且同一秒内再次登录时为 0
+	require.NoError(t, env.DB.Callback().Update().After("gorm:update").Register("test:unchanged_rows", func(db *gorm.DB) {
+		if db.Statement.Table == "user_passkey" {
+			db.RowsAffected = 0
+		}
+	}))
+
+	// 同步通行密钥的计数器始终为 0，同一秒内连续登录也都能成功
+	for i := 0; i < 3; i++ {
+		options, err := s.BeginLogin()
+		require.NoError(t, err)
+		_, err = s.FinishLogin(&model.PasskeyLoginRequest{SessionId: options.SessionId, Credential: authenticator.assert(t, options.Options, user.ID)}, &model.ClientInfo{})
+		require.NoError(t, err)
+	}
+}
+
+func TestConfirmReset_RevokesPasskeys(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	user := createPasswordUser(t, env, "alice", "s3cret-Passw0rd")
+	registerPasskey(t, env, user, "s3cret-Passw0rd")
+
+	require.NoError(t, env.Redis.Set(passwordResetKeyPrefix+utils.HashToken("reset-token"), fmt.Sprint(user.ID)))
+	require.NoError(t, (&PasswordResetService{}).ConfirmReset(&model.PasswordResetConfirmRequest{Token: "reset-token", NewPassword: "n3w-Passw0rd"}))
+
+	var count int64
+	require.NoError(t, env.DB.Model(&model.Passkey{}).Where("user_id = ?", user.ID).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestPasskeyLogin_AccountChecks(t *testing.T) {
+	conf := testenv.Config(t)
+	conf.User.RequireVerifiedEmail = true
+	conf.LoginLock.MaxFailures = 3
+	env := testenv.Setup(t, conf)
+	s := &PasskeyService{}
+	user := createPasswordUser(t, env, "alice", "s3cret-Passw0rd")
+	authenticator := registerPasskey(t, env, user, "s3cret-Passw0rd")
+	client := &model.ClientInfo{IP: "192.0.2.1"}
+
+	login := func() error {
+		options, err := s.BeginLogin()
+		require.NoError(t, err)
+		_, err = s.FinishLogin(&model.PasskeyLoginRequest{SessionId: options.SessionId, Credential: authenticator.login(t, options.Options, user.ID)}, client)
+		return err
+	}
+
+	// 与密码登录相同，邮箱未验证的用户不能登录
+	require.NoError(t, env.DB.Model(user).Update("email_verified", false).Error)
+	assert.ErrorIs(t, login(), ErrEmailNotVerified)
+	require.NoError(t, env.DB.Model(user).Update("email_verified", true).Error)
+
+	// 账号被锁定期间不能用通行密钥绕过
+	guard := &LoginGuardService{}
+	for i := 0; i < conf.LoginLock.MaxFailures; i++ {
+		require.NoError(t, guard.RecordFailure(accountSubject(user.ID, ""), "198.51.100.7"))
+	}
+	assert.ErrorIs(t, login(), ErrLoginLocked)
+
+	require.NoError(t, guard.Unlock(user.ID))
+	assert.NoError(t, login())
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/mailer"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const passwordResetKeyPrefix = "password_reset:" // 密码重置令牌，key 为令牌摘要
+
+var (
+	ErrTooManyResetRequests = errors.New("请求过于频繁，请稍后重试")
+	ErrResetTokenInvalid    = errors.New("重置链接无效或已过期")
+)
+
+// PasswordResetService 找回密码服务
+type PasswordResetService struct{}
+
+// RequestReset 申请重置密码：生成一次性重置令牌并发送邮件
+// 无论账号是否存在都返回成功，避免泄露账号信息；按账号和 IP 分别限流
+func (s *PasswordResetService) RequestReset(req *model.PasswordResetRequest, clientIP string) error {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	conf := application.GetConfig().PwdReset
+	ctx := context.Background()
+
+	allowed, err := utils.AllowRequest(ctx, rdb, "password_reset:ip:"+clientIP, conf.IPLimit, time.Hour)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrTooManyResetRequests
+	}
+
+	// 按提交的账号限流，不区分账号是否存在
+	allowed, err = utils.AllowRequest(ctx, rdb, "password_reset:account:"+strings.ToLower(req.Username), conf.AccountLimit, time.Hour)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrTooManyResetRequests
+	}
+
+	var user model.User
+	if err := whereLoginName(db, req.Username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// 只向已验证的邮箱发送重置邮件；目录用户的密码在目录中重置
+	if user.Email == "" || !user.Verified {
+		return nil
+	}
+	if managed, err := ldapManaged(db, user.ID); err != nil || managed {
+		return err
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(conf.TokenTTL) * time.Minute
+	if err := rdb.Set(ctx, passwordResetKeyPrefix+utils.HashToken(token), user.ID, ttl).Err(); err != nil {
+		return err
+	}
+
+	return application.GetMailer().Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "重置密码",
+		Body: fmt.Sprintf("您好 %s：\n\n我们收到了重置密码的请求，请在 %d 分钟内打开以下链接设置新密码：\n\n%s\n\n如果这不是您本人的操作，请忽略本邮件。",
+			user.Username, conf.TokenTTL, fmt.Sprintf(conf.URL, token)),
+	})
+}
+
+// ConfirmReset 使用重置令牌设置新密码，令牌只能使用一次，成功后吊销该用户的全部会话
+func (s *PasswordResetService) ConfirmReset(req *model.PasswordResetConfirmRequest) error {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	// 先只读取令牌，新密码不满足策略时令牌仍然有效，用户可以修改后重新提交
+	key := passwordResetKeyPrefix + utils.HashToken(req.Token)
+	userId, err := rdb.Get(ctx, key).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrResetTokenInvalid
+		}
+		return err
+	}
+
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrResetTokenInvalid
+		}
+		return err
+	}
+
+	if managed, err := ldapManaged(db, user.ID); err != nil {
+		return err
+	} else if managed {
+		return ErrLDAPManagedPassword
+	}
+	if err := utils.ValidatePassword(user.Username, req.NewPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	// 修改密码前才核销令牌，DEL 只会对一个并发请求返回 1，保证令牌只能被使用一次
+	if deleted, err := rdb.Del(ctx, key).Result(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return ErrResetTokenInvalid
+	}
+
+	// 账号可能已被他人控制，重置密码时一并删除通行密钥，避免对方保留无需密码的登录方式
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password":             hashedPassword,
+			"password_change_time": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&model.Passkey{}).Error
+	}); err != nil {
+		return err
+	}
+
+	return (&TokenService{}).RevokeAllSessions(user.ID)
+}
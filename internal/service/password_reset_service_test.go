@@ -0,0 +1,40 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+	"users-by-go-example/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmReset(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	user := env.CreateUser(t, "alice")
+
+	key := passwordResetKeyPrefix + utils.HashToken("reset-token")
+	require.NoError(t, env.Redis.Set(key, fmt.Sprint(user.ID)))
+
+	s := &PasswordResetService{}
+
+	// 新密码不满足策略时令牌不被核销
+	err := s.ConfirmReset(&model.PasswordResetConfirmRequest{Token: "reset-token", NewPassword: "alice"})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrResetTokenInvalid)
+	assert.True(t, env.Redis.Exists(key))
+
+	require.NoError(t, s.ConfirmReset(&model.PasswordResetConfirmRequest{Token: "reset-token", NewPassword: "n3w-Passw0rd"}))
+	assert.False(t, env.Redis.Exists(key))
+
+	var stored model.User
+	require.NoError(t, env.DB.First(&stored, user.ID).Error)
+	ok, _, _ := utils.VerifyPassword(stored.Password, "n3w-Passw0rd")
+	assert.True(t, ok)
+
+	// 令牌只能使用一次
+	err = s.ConfirmReset(&model.PasswordResetConfirmRequest{Token: "reset-token", NewPassword: "an0ther-Passw0rd"})
+	assert.ErrorIs(t, err, ErrResetTokenInvalid)
+}
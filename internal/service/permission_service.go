@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/model"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const userPermitsKeyPrefix = "user_permits:" // 用户有效权限缓存
+
+// 权限缓存命中统计
+var (
+	permitsCacheHits   atomic.Int64
+	permitsCacheMisses atomic.Int64
+)
+
+// PermissionService 权限服务
+type PermissionService struct{}
+
+// GetCachedUserPermits 优先从 Redis 读取用户的有效权限，未命中时查库并回填缓存
+func (s *PermissionService) GetCachedUserPermits(userId int64) (permits []string, hit bool, err error) {
+	ttl := time.Duration(application.GetConfig().Permission.CacheTTL) * time.Second
+	if ttl <= 0 {
+		permits, err = s.GetUserPermits(userId)
+		return permits, false, err
+	}
+
+	rdb := application.GetRedis()
+	ctx := context.Background()
+	key := fmt.Sprintf("%s%d", userPermitsKeyPrefix, userId)
+
+	data, err := rdb.Get(ctx, key).Bytes()
+	if err == nil && json.Unmarshal(data, &permits) == nil {
+		permitsCacheHits.Add(1)
+		return permits, true, nil
+	}
+	// Redis 不可用时降级为查库
+	if err != nil && !errors.Is(err, redis.Nil) {
+		permits, err = s.GetUserPermits(userId)
+		return permits, false, err
+	}
+
+	permitsCacheMisses.Add(1)
+	permits, err = s.GetUserPermits(userId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 空权限集也缓存，避免无权限用户每次请求都查库
+	if permits == nil {
+		permits = []string{}
+	}
+	if data, err = json.Marshal(permits); err == nil {
+		rdb.Set(ctx, key, data, ttl)
+	}
+	return permits, false, nil
+}
+
+// PermitsCacheStats 返回权限缓存的累计命中和未命中次数
+func PermitsCacheStats() (hits, misses int64) {
+	return permitsCacheHits.Load(), permitsCacheMisses.Load()
+}
+
+// GetUserPermits 获取用户的有效权限标识：直接授予的权限与角色授予的权限的并集
+func (s *PermissionService) GetUserPermits(userId int64) ([]string, error) {
+	db := application.GetDB()
+
+	var permits []string
+	if err := db.Raw(`
+		SELECT p.permit
+		FROM permission p
+		JOIN user_permission up ON p.id = up.permission_id
+		WHERE up.user_id = ?
+		UNION
+		SELECT p.permit
+		FROM permission p
+		JOIN role_permission rp ON p.id = rp.permission_id
+		JOIN user_role ur ON rp.role_id = ur.role_id
+		WHERE ur.user_id = ?`, userId, userId).Scan(&permits).Error; err != nil {
+		return nil, err
+	}
+	return permits, nil
+}
+
+// ListPermissions 获取全部权限
+func (s *PermissionService) ListPermissions() ([]*model.Permission, error) {
+	db := application.GetDB()
+
+	var permissions []*model.Permission
+	if err := db.Order("id").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// CreatePermission 创建权限
+func (s *PermissionService) CreatePermission(req *model.CreatePermissionRequest) (*model.Permission, error) {
+	db := application.GetDB()
+
+	var count int64
+	if err := db.Model(&model.Permission{}).Where("permit = ?", req.Permit).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("权限标识已存在")
+	}
+
+	permission := &model.Permission{
+		Permit: req.Permit,
+		Name:   req.Name,
+	}
+	if err := db.Create(permission).Error; err != nil {
+		return nil, err
+	}
+	return permission, nil
+}
+
+// DeletePermission 删除权限，同时清理用户和角色上的授权
+func (s *PermissionService) DeletePermission(id int64) error {
+	db := application.GetDB()
+
+	var userIds []int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(&model.Permission{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("权限不存在")
+		}
+
+		// 在事务内用加锁读找出受影响的用户，用于清理权限缓存；并发的授权要么被读到，要么等本事务提交后再执行
+		locking := clause.Locking{Strength: "UPDATE"}
+		if err := tx.Clauses(locking).Model(&model.UserPermission{}).
+			Where("permission_id = ?", id).Pluck("user_id", &userIds).Error; err != nil {
+			return err
+		}
+		var roleIds []int64
+		if err := tx.Clauses(locking).Model(&model.RolePermission{}).
+			Where("permission_id = ?", id).Pluck("role_id", &roleIds).Error; err != nil {
+			return err
+		}
+		if len(roleIds) > 0 {
+			var roleUserIds []int64
+			if err := tx.Clauses(locking).Model(&model.UserRole{}).
+				Where("role_id IN ?", roleIds).Pluck("user_id", &roleUserIds).Error; err != nil {
+				return err
+			}
+			userIds = append(userIds, roleUserIds...)
+		}
+
+		if err := tx.Where("permission_id = ?", id).Delete(&model.UserPermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("permission_id = ?", id).Delete(&model.RolePermission{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return invalidateUserPermits(userIds...)
+}
+
+// ListUserPermissions 获取直接授予用户的权限（不含角色授予的权限）
+func (s *PermissionService) ListUserPermissions(userId int64) ([]*model.Permission, error) {
+	db := application.GetDB()
+
+	var permissions []*model.Permission
+	if err := db.Raw(`
+		SELECT p.*
+		FROM permission p
+		JOIN user_permission up ON p.id = up.permission_id
+		WHERE up.user_id = ?
+		ORDER BY p.id`, userId).Scan(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// GrantUserPermission 给用户授予权限
+func (s *PermissionService) GrantUserPermission(userId, permissionId int64) error {
+	db := application.GetDB()
+
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		return err
+	}
+	if err := mustExist(db, &model.Permission{}, permissionId, "权限不存在"); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&model.UserPermission{}).Where("user_id = ? AND permission_id = ?", userId, permissionId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if err := db.Create(&model.UserPermission{UserId: userId, PermissionId: permissionId}).Error; err != nil {
+		return err
+	}
+
+	return invalidateUserPermits(userId)
+}
+
+// RevokeUserPermission 取消用户的权限
+func (s *PermissionService) RevokeUserPermission(userId, permissionId int64) error {
+	db := application.GetDB()
+
+	if err := db.Where("user_id = ? AND permission_id = ?", userId, permissionId).Delete(&model.UserPermission{}).Error; err != nil {
+		return err
+	}
+
+	return invalidateUserPermits(userId)
+}
+
+// invalidateUserPermits 授权变更后清理相关用户的权限缓存
+func invalidateUserPermits(userIds ...int64) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(userIds))
+	for _, userId := range userIds {
+		keys = append(keys, fmt.Sprintf("%s%d", userPermitsKeyPrefix, userId))
+	}
+	return application.GetRedis().Del(context.Background(), keys...).Err()
+}
+
+// invalidateRoleUsersPermits 角色授权变更后清理该角色下所有用户的权限缓存
+func invalidateRoleUsersPermits(db *gorm.DB, roleId int64) error {
+	var userIds []int64
+	if err := db.Model(&model.UserRole{}).Where("role_id = ?", roleId).Pluck("user_id", &userIds).Error; err != nil {
+		return err
+	}
+	return invalidateUserPermits(userIds...)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"errors"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// RoleService 角色服务
+type RoleService struct{}
+
+// ListRoles 获取全部角色
+func (s *RoleService) ListRoles() ([]*model.Role, error) {
+	db := application.GetDB()
+
+	var roles []*model.Role
+	if err := db.Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// CreateRole 创建角色
+func (s *RoleService) CreateRole(req *model.CreateRoleRequest) (*model.Role, error) {
+	db := application.GetDB()
+
+	var count int64
+	if err := db.Model(&model.Role{}).Where("code = ?", req.Code).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("角色编码已存在")
+	}
+
+	role := &model.Role{
+		Code: req.Code,
+		Name: req.Name,
+	}
+	if err := db.Create(role).Error; err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole 删除角色，同时清理角色的授权和用户关联
+func (s *RoleService) DeleteRole(id int64) error {
+	db := application.GetDB()
+
+	// 删除前找出角色下的用户，用于清理权限缓存
+	var userIds []int64
+	if err := db.Model(&model.UserRole{}).Where("role_id = ?", id).Pluck("user_id", &userIds).Error; err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(&model.Role{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("角色不存在")
+		}
+		if err := tx.Where("role_id = ?", id).Delete(&model.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("role_id = ?", id).Delete(&model.UserRole{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return invalidateUserPermits(userIds...)
+}
+
+// ListRolePermissions 获取角色拥有的权限
+func (s *RoleService) ListRolePermissions(roleId int64) ([]*model.Permission, error) {
+	db := application.GetDB()
+
+	var permissions []*model.Permission
+	if err := db.Raw(`
+		SELECT p.*
+		FROM permission p
+		JOIN role_permission rp ON p.id = rp.permission_id
+		WHERE rp.role_id = ?
+		ORDER BY p.id`, roleId).Scan(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// GrantPermission 给角色授予权限
+func (s *RoleService) GrantPermission(roleId, permissionId int64) error {
+	db := application.GetDB()
+
+	if err := mustExist(db, &model.Role{}, roleId, "角色不存在"); err != nil {
+		return err
+	}
+	if err := mustExist(db, &model.Permission{}, permissionId, "权限不存在"); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&model.RolePermission{}).Where("role_id = ? AND permission_id = ?", roleId, permissionId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if err := db.Create(&model.RolePermission{RoleId: roleId, PermissionId: permissionId}).Error; err != nil {
+		return err
+	}
+
+	return invalidateRoleUsersPermits(db, roleId)
+}
+
+// RevokePermission 取消角色的权限
+func (s *RoleService) RevokePermission(roleId, permissionId int64) error {
+	db := application.GetDB()
+
+	if err := db.Where("role_id = ? AND permission_id = ?", roleId, permissionId).Delete(&model.RolePermission{}).Error; err != nil {
+		return err
+	}
+
+	return invalidateRoleUsersPermits(db, roleId)
+}
+
+// AssignRole 给用户分配角色
+func (s *RoleService) AssignRole(userId, roleId int64) error {
+	db := application.GetDB()
+
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		return err
+	}
+	if err := mustExist(db, &model.Role{}, roleId, "角色不存在"); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&model.UserRole{}).Where("user_id = ? AND role_id = ?", userId, roleId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if err := db.Create(&model.UserRole{UserId: userId, RoleId: roleId}).Error; err != nil {
+		return err
+	}
+
+	return invalidateUserPermits(userId)
+}
+
+// RemoveRole 移除用户的角色
+func (s *RoleService) RemoveRole(userId, roleId int64) error {
+	db := application.GetDB()
+
+	if err := db.Where("user_id = ? AND role_id = ?", userId, roleId).Delete(&model.UserRole{}).Error; err != nil {
+		return err
+	}
+
+	return invalidateUserPermits(userId)
+}
+
+// mustExist 检查指定 id 的记录是否存在
+func mustExist(db *gorm.DB, value any, id int64, message string) error {
+	var count int64
+	if err := db.Model(value).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New(message)
+	}
+	return nil
+}
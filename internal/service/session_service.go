@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	userSessionsKeyPrefix = "user_sessions:" // 用户的会话 ID 集合，用于列出会话
+	sessionSeenKeyPrefix  = "session_seen:"  // 最近活跃时间的更新节流标记
+	sessionSeenInterval   = time.Minute
+)
+
+var ErrSessionNotFound = errors.New("会话不存在或已失效")
+
+// sessionRecord 会话在 Redis 中的存储结构，保存在令牌家族 key 中，家族被删除即会话失效
+type sessionRecord struct {
+	UserID       int64     `json:"userId"`
+	Generation   int64     `json:"gen"`
+	IP           string    `json:"ip"` // 登录时的 IP，之后不再修改
+	UserAgent    string    `json:"userAgent"`
+	CreateTime   time.Time `json:"createTime"`
+	LastSeenTime time.Time `json:"lastSeenTime"`
+	LastSeenIP   string    `json:"lastSeenIp"`
+}
+
+// SessionService 登录会话服务
+type SessionService struct{}
+
+// createSession 登录时创建会话
+func createSession(ctx context.Context, sessionID string, userID, generation int64, client *model.ClientInfo) error {
+	rdb := application.GetRedis()
+
+	now := time.Now()
+	record := &sessionRecord{
+		UserID:       userID,
+		Generation:   generation,
+		CreateTime:   now,
+		LastSeenTime: now,
+	}
+	if client != nil {
+		record.IP = client.IP
+		record.LastSeenIP = client.IP
+		record.UserAgent = client.UserAgent
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	ttl := utils.RefreshTokenTTL()
+	setKey := fmt.Sprintf("%s%d", userSessionsKeyPrefix, userID)
+	pipe := rdb.TxPipeline()
+	pipe.Set(ctx, refreshFamilyKeyPrefix+sessionID, data, ttl)
+	pipe.SAdd(ctx, setKey, sessionID)
+	pipe.Expire(ctx, setKey, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// deleteSession 删除会话，会话下的刷新令牌和访问令牌随之失效
+func deleteSession(ctx context.Context, userID int64, sessionID string) error {
+	pipe := application.GetRedis().TxPipeline()
+	pipe.Del(ctx, refreshFamilyKeyPrefix+sessionID)
+	pipe.SRem(ctx, fmt.Sprintf("%s%d", userSessionsKeyPrefix, userID), sessionID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// List 列出用户的有效会话，顺带清理已过期或已被吊销的会话
+func (s *SessionService) List(userID int64, currentID string) ([]*model.Session, error) {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	setKey := fmt.Sprintf("%s%d", userSessionsKeyPrefix, userID)
+	ids, err := rdb.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []*model.Session{}, nil
+	}
+
+	generation, err := (&TokenService{}).currentGeneration(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, refreshFamilyKeyPrefix+id)
+	}
+	values, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*model.Session, 0, len(ids))
+	var stale []any
+	for i, value := range values {
+		str, ok := value.(string)
+		var record sessionRecord
+		if !ok || json.Unmarshal([]byte(str), &record) != nil || record.Generation < generation {
+			stale = append(stale, ids[i])
+			continue
+		}
+		lastSeenIP := record.LastSeenIP
+		if lastSeenIP == "" {
+			lastSeenIP = record.IP
+		}
+		sessions = append(sessions, &model.Session{
+			ID:           ids[i],
+			IP:           record.IP,
+			UserAgent:    record.UserAgent,
+			CreateTime:   record.CreateTime,
+			LastSeenTime: record.LastSeenTime,
+			LastSeenIP:   lastSeenIP,
+			Current:      ids[i] == currentID,
+		})
+	}
+	if len(stale) > 0 {
+		rdb.SRem(ctx, setKey, stale...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenTime.After(sessions[j].LastSeenTime)
+	})
+	return sessions, nil
+}
+
+// Revoke 吊销用户自己的某个会话
+func (s *SessionService) Revoke(userID int64, sessionID string) error {
+	ctx := context.Background()
+
+	isMember, err := application.GetRedis().SIsMember(ctx, fmt.Sprintf("%s%d", userSessionsKeyPrefix, userID), sessionID).Result()
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrSessionNotFound
+	}
+	return deleteSession(ctx, userID, sessionID)
+}
+
+// RevokeOthers 吊销除当前会话外的全部会话
+func (s *SessionService) RevokeOthers(userID int64, currentID string) (int, error) {
+	ctx := context.Background()
+
+	ids, err := application.GetRedis().SMembers(ctx, fmt.Sprintf("%s%d", userSessionsKeyPrefix, userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, id := range ids {
+		if id == currentID {
+			continue
+		}
+		if err := deleteSession(ctx, userID, id); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Touch 更新会话的最近活跃时间和 IP，登录时的 IP 保持不变，每个会话每分钟最多更新一次
+func (s *SessionService) Touch(sessionID, ip string) {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	ok, err := rdb.SetNX(ctx, sessionSeenKeyPrefix+sessionID, 1, sessionSeenInterval).Result()
+	if err != nil || !ok {
+		return
+	}
+
+	key := refreshFamilyKeyPrefix + sessionID
+	data, err := rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		return
+	}
+	var record sessionRecord
+	if json.Unmarshal(data, &record) != nil {
+		return
+	}
+	record.LastSeenTime = time.Now()
+	if ip != "" {
+		record.LastSeenIP = ip
+	}
+	if data, err = json.Marshal(&record); err == nil {
+		// 只在会话仍存在时更新，避免复活刚被吊销的会话
+		rdb.SetArgs(ctx, key, data, redis.SetArgs{KeepTTL: true, Mode: "XX"})
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+	"users-by-go-example/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionTouch_KeepsLoginIP(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &SessionService{}
+	user := env.CreateUser(t, "alice")
+
+	resp, err := (&TokenService{}).IssueTokenPair(user.ID, user.Username, &model.ClientInfo{IP: "192.0.2.1", UserAgent: "laptop"})
+	require.NoError(t, err)
+	claims, err := utils.ParseToken(resp.Token)
+	require.NoError(t, err)
+
+	// 之后的请求来自其他 IP，登录时的 IP 仍然保留
+	s.Touch(claims.SessionID, "198.51.100.7")
+	sessions, err := s.List(user.ID, claims.SessionID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "192.0.2.1", sessions[0].IP)
+	assert.Equal(t, "198.51.100.7", sessions[0].LastSeenIP)
+	assert.True(t, sessions[0].Current)
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	refreshTokenKeyPrefix    = "refresh_token:"    // 刷新令牌记录，key 为令牌摘要
+	refreshFamilyKeyPrefix   = "refresh_family:"   // 令牌家族（即登录会话），存在即表示该家族有效，值为会话信息
+	revokedTokenKeyPrefix    = "token_revoked:"    // 已吊销的访问令牌，key 为 jti
+	tokenGenerationKeyPrefix = "token_generation:" // 用户当前令牌代数，递增即吊销该用户全部令牌
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+	ErrRefreshTokenReused  = errors.New("刷新令牌已被使用，相关会话已全部失效，请重新登录")
+	ErrTokenRevoked        = errors.New("认证令牌已失效")
+)
+
+// refreshTokenRecord 刷新令牌在 Redis 中的存储结构
+type refreshTokenRecord struct {
+	UserID     int64  `json:"userId"`
+	Username   string `json:"username"`
+	FamilyID   string `json:"familyId"`
+	Generation int64  `json:"gen"`
+	Used       bool   `json:"used"` // 已轮换过的令牌保留到过期，用于检测重放
+}
+
+// TokenService 令牌服务
+type TokenService struct{}
+
+// IssueTokenPair 签发访问令牌和刷新令牌，每次登录产生一个新的令牌家族，同时记录为一个登录会话
+func (s *TokenService) IssueTokenPair(userID int64, username string, client *model.ClientInfo) (*model.TokenResponse, error) {
+	ctx := context.Background()
+
+	generation, err := s.currentGeneration(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	familyID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	if err := createSession(ctx, familyID, userID, generation, client); err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, &refreshTokenRecord{
+		UserID:     userID,
+		Username:   username,
+		FamilyID:   familyID,
+		Generation: generation,
+	})
+}
+
+// Refresh 使用刷新令牌换取新的令牌对
+// 刷新令牌每次使用后立即轮换；已轮换的令牌再次出现时视为泄露，整个令牌家族被吊销
+func (s *TokenService) Refresh(refreshToken string) (*model.TokenResponse, error) {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	key := refreshTokenKeyPrefix + utils.HashToken(refreshToken)
+
+	lock := utils.NewRedisLock(rdb, key, 5*time.Second)
+	if err := lock.TryLock(ctx, 3, 50*time.Millisecond); err != nil {
+		if errors.Is(err, utils.ErrLockFailed) {
+			return nil, errors.New("系统繁忙，请稍后重试")
+		}
+		return nil, err
+	}
+	defer lock.Unlock(ctx)
+
+	data, err := rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	var record refreshTokenRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	familyKey := refreshFamilyKeyPrefix + record.FamilyID
+	exists, err := rdb.Exists(ctx, familyKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	generation, err := s.currentGeneration(ctx, record.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Generation < generation {
+		deleteSession(ctx, record.UserID, record.FamilyID)
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	if record.Used {
+		if err := deleteSession(ctx, record.UserID, record.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	record.Used = true
+	data, err = json.Marshal(&record)
+	if err != nil {
+		return nil, err
+	}
+	if err := rdb.SetArgs(ctx, key, data, redis.SetArgs{KeepTTL: true}).Err(); err != nil {
+		return nil, err
+	}
+
+	// 用户已被删除时不再续签
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", record.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			deleteSession(ctx, record.UserID, record.FamilyID)
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	if err := rdb.Expire(ctx, familyKey, utils.RefreshTokenTTL()).Err(); err != nil {
+		return nil, err
+	}
+	rdb.Expire(ctx, fmt.Sprintf("%s%d", userSessionsKeyPrefix, user.ID), utils.RefreshTokenTTL())
+
+	return s.issue(ctx, &refreshTokenRecord{
+		UserID:     user.ID,
+		Username:   user.Username,
+		FamilyID:   record.FamilyID,
+		Generation: record.Generation,
+	})
+}
+
+// CheckAccessToken 检查访问令牌是否已被吊销（单个吊销、所属会话被吊销或用户全部会话吊销）
+func (s *TokenService) CheckAccessToken(claims *utils.Claims) error {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	keys := []string{
+		revokedTokenKeyPrefix + claims.ID,
+		fmt.Sprintf("%s%d", tokenGenerationKeyPrefix, claims.UserID),
+	}
+	if claims.Actor != nil {
+		keys = append(keys, fmt.Sprintf("%s%d", tokenGenerationKeyPrefix, claims.Actor.UserID))
+	}
+	if claims.SessionID != "" {
+		keys = append(keys, refreshFamilyKeyPrefix+claims.SessionID)
+	}
+	values, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return err
+	}
+	if claims.SessionID != "" && values[len(values)-1] == nil {
+		return ErrTokenRevoked
+	}
+
+	if values[0] != nil {
+		return ErrTokenRevoked
+	}
+	revoked, err := generationRevoked(values[1], claims.Generation)
+	if err != nil {
+		return err
+	}
+	// 模拟令牌同时受操作人的令牌代数约束
+	if !revoked && claims.Actor != nil {
+		if revoked, err = generationRevoked(values[2], claims.Actor.Generation); err != nil {
+			return err
+		}
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// generationRevoked 令牌签发时的代数是否小于当前代数，current 为 MGET 返回的值
+func generationRevoked(current any, generation int64) (bool, error) {
+	if current == nil {
+		return false, nil
+	}
+	value, err := strconv.ParseInt(current.(string), 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return generation < value, nil
+}
+
+// Logout 退出登录：吊销当前访问令牌，并吊销刷新令牌所属的令牌家族
+func (s *TokenService) Logout(claims *utils.Claims, refreshToken string) error {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	// 吊销记录只需保留到令牌自然过期
+	if ttl := time.Until(claims.ExpiresAt.Time); ttl > 0 {
+		if err := rdb.Set(ctx, revokedTokenKeyPrefix+claims.ID, claims.UserID, ttl).Err(); err != nil {
+			return err
+		}
+	}
+
+	// 模拟令牌没有刷新令牌，不允许借此吊销被模拟用户的会话
+	if refreshToken == "" || claims.Actor != nil {
+		return nil
+	}
+
+	data, err := rdb.Get(ctx, refreshTokenKeyPrefix+utils.HashToken(refreshToken)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return err
+	}
+
+	var record refreshTokenRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	// 只允许吊销自己的令牌家族
+	if record.UserID != claims.UserID {
+		return nil
+	}
+	return deleteSession(ctx, record.UserID, record.FamilyID)
+}
+
+// RevokeAllSessions 吊销用户的全部会话：递增令牌代数，此前签发的访问令牌和刷新令牌全部失效
+// 个人 API Key 可能是用被盗的会话创建的，一并吊销；服务密钥由管理员管理，不受影响
+func (s *TokenService) RevokeAllSessions(userID int64) error {
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	if err := application.GetDB().Model(&model.ApiKey{}).
+		Where("user_id = ? AND type = ? AND revoked = ?", userID, model.ApiKeyTypePersonal, false).
+		Update("revoked", true).Error; err != nil {
+		return err
+	}
+
+	return rdb.Incr(ctx, fmt.Sprintf("%s%d", tokenGenerationKeyPrefix, userID)).Err()
+}
+
+// currentGeneration 获取用户当前的令牌代数，未设置时为 0
+func (s *TokenService) currentGeneration(ctx context.Context, userID int64) (int64, error) {
+	generation, err := application.GetRedis().Get(ctx, fmt.Sprintf("%s%d", tokenGenerationKeyPrefix, userID)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return generation, nil
+}
+
+// issue 签发访问令牌并保存新的刷新令牌
+func (s *TokenService) issue(ctx context.Context, record *refreshTokenRecord) (*model.TokenResponse, error) {
+	rdb := application.GetRedis()
+
+	accessToken, err := utils.GenerateToken(record.UserID, record.Username, record.Generation, record.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	if err := rdb.Set(ctx, refreshTokenKeyPrefix+utils.HashToken(refreshToken), data, utils.RefreshTokenTTL()).Err(); err != nil {
+		return nil, err
+	}
+
+	return &model.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+	}, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+	"users-by-go-example/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerationRevoked(t *testing.T) {
+	cases := []struct {
+		name       string
+		current    any
+		generation int64
+		revoked    bool
+	}{
+		{"从未吊销", nil, 0, false},
+		{"签发于当前代", "2", 2, false},
+		{"签发于旧代", "2", 1, true},
+		{"签发于更新的代", "2", 3, false},
+	}
+	for _, c := range cases {
+		revoked, err := generationRevoked(c.current, c.generation)
+		require.NoError(t, err, c.name)
+		assert.Equal(t, c.revoked, revoked, c.name)
+	}
+
+	_, err := generationRevoked("abc", 1)
+	assert.Error(t, err)
+}
+
+func TestCheckAccessToken_Impersonation(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	s := &TokenService{}
+	actor := env.CreateUser(t, "admin", "user:impersonate", "user:get")
+	user := env.CreateUser(t, "alice", "user:get")
+
+	start := func() *utils.Claims {
+		resp, err := (&ImpersonationService{}).Start(actor.ID, actor.Username, &model.ImpersonateRequest{UserId: user.ID, Reason: "工单 #1"}, "192.0.2.1")
+		require.NoError(t, err)
+		claims, err := utils.ParseToken(resp.Token)
+		require.NoError(t, err)
+		return claims
+	}
+
+	claims := start()
+	require.NotNil(t, claims.Actor)
+	assert.Equal(t, actor.ID, claims.Actor.UserID)
+	assert.NoError(t, s.CheckAccessToken(claims))
+
+	// 操作人吊销全部会话后模拟令牌失效
+	require.NoError(t, s.RevokeAllSessions(actor.ID))
+	assert.ErrorIs(t, s.CheckAccessToken(claims), ErrTokenRevoked)
+
+	// 被模拟的用户吊销全部会话后同样失效
+	claims = start()
+	assert.NoError(t, s.CheckAccessToken(claims))
+	require.NoError(t, s.RevokeAllSessions(user.ID))
+	assert.ErrorIs(t, s.CheckAccessToken(claims), ErrTokenRevoked)
+
+	// 调用 /logout 结束模拟
+	claims = start()
+	require.NoError(t, s.Logout(claims, ""))
+	assert.ErrorIs(t, s.CheckAccessToken(claims), ErrTokenRevoked)
+
+	var count int64
+	require.NoError(t, env.DB.Model(&model.ImpersonationLog{}).Where("actor_id = ? AND user_id = ?", actor.ID, user.ID).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+}
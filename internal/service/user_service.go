@@ -4,15 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 	"users-by-go-example/internal/application"
 	"users-by-go-example/internal/model"
 	"users-by-go-example/utils"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// ErrEmailNotVerified 开启 user.require_verified_email 时，邮箱未验证的本地用户不能登录
+var ErrEmailNotVerified = errors.New("邮箱尚未验证，请先完成邮箱验证")
+
 // UserService 用户服务
 type UserService struct{}
 
@@ -49,7 +52,19 @@ func (s *UserService) Register(req *model.RegisterRequest) (*model.UserResponse,
 		return nil, errors.New("用户名已存在")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if req.Email != "" {
+		if err := checkEmailAvailable(tx, req.Email, 0); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := utils.ValidatePassword(req.Username, req.Password); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -57,9 +72,11 @@ func (s *UserService) Register(req *model.RegisterRequest) (*model.UserResponse,
 
 	user := &model.User{
 		Username: req.Username,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 		NikeName: req.NikeName,
 		Delete:   0,
+		// 邮箱验证通过后才生效
+		PendingEmail: req.Email,
 	}
 
 	if err := tx.Create(user).Error; err != nil {
@@ -72,31 +89,116 @@ func (s *UserService) Register(req *model.RegisterRequest) (*model.UserResponse,
 		return nil, err
 	}
 
+	// 验证邮件发送失败不影响注册，用户可以稍后重新发送
+	if user.PendingEmail != "" {
+		_ = (&EmailService{}).SendVerification(user.ID)
+	}
+
 	return user.ToResponse(), nil
 }
 
-// Login 用户登录
-func (s *UserService) Login(req *model.LoginRequest) (string, error) {
+// Login 用户登录，支持用户名或邮箱，成功后签发访问令牌和刷新令牌
+// 密码按 auth.chain 配置的校验链依次校验（本地密码、LDAP）
+// 开启两步验证的用户只返回待完成令牌，需调用 /login/mfa 提交验证码
+// 按账号和客户端 IP 统计失败次数，超过阈值后临时锁定
+func (s *UserService) Login(req *model.LoginRequest, client *model.ClientInfo) (*model.LoginResponse, error) {
 	db := application.GetDB()
+	guard := &LoginGuardService{}
 
-	var user model.User
-	if err := db.Raw("SELECT id,username,password FROM users WHERE username = ? AND `delete` = 0", req.Username).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", errors.New("用户名或密码错误")
+	var userId int64
+	if err := whereLoginName(db.Model(&model.User{}), req.Username).Select("id").Scan(&userId).Error; err != nil {
+		return nil, err
+	}
+
+	account := accountSubject(userId, req.Username)
+	if err := guard.Check(account, client.IP); err != nil {
+		return nil, err
+	}
+
+	user, authenticator, err := authenticate(req.Username, req.Password)
+	if err != nil {
+		// 目录服务不可用不计入失败次数
+		if errors.Is(err, ErrInvalidCredentials) {
+			if err := guard.RecordFailure(account, client.IP); err != nil {
+				return nil, err
+			}
 		}
-		return "", err
+		return nil, err
+	}
+
+	// 开启两步验证的用户在第二步通过后才清空失败计数，否则重新输入密码就能重置验证码的失败次数
+	if !user.TotpEnabled {
+		if err := guard.RecordSuccess(account); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkLoginAllowed(user, authenticator); err != nil {
+		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return "", errors.New("用户名或密码错误")
+	return completeLogin(user, client)
+}
+
+// checkLoginAllowed 身份校验通过后、签发令牌前的账号状态检查，密码登录和通行密钥登录共用
+// 外部目录中的用户邮箱由目录维护，不要求在本系统验证
+func checkLoginAllowed(user *model.User, authenticator string) error {
+	if user.Delete != 0 {
+		return ErrInvalidCredentials
+	}
+	if authenticator == "local" && application.GetConfig().User.RequireVerifiedEmail && !user.Verified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// completeLogin 身份校验通过后完成登录：开启两步验证的用户返回待完成令牌，否则直接签发令牌
+func completeLogin(user *model.User, client *model.ClientInfo) (*model.LoginResponse, error) {
+	if user.TotpEnabled {
+		mfaToken, err := (&MfaService{}).CreatePendingLogin(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResponse{MfaRequired: true, MfaToken: mfaToken}, nil
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Username)
+	tokens, err := (&TokenService{}).IssueTokenPair(user.ID, user.Username, client)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return &model.LoginResponse{TokenResponse: tokens}, nil
+}
+
+// whereLoginName 按登录名匹配未删除的用户：包含 @ 时只匹配已验证的邮箱，否则只匹配用户名
+// 注册时用户名不能包含 @，两种匹配互不重叠，不会因为某人的用户名与他人的邮箱相同而匹配到错误的账号
+func whereLoginName(db *gorm.DB, login string) *gorm.DB {
+	if strings.Contains(login, "@") {
+		return db.Where("email = ? AND email_verified = ? AND `delete` = 0", login, true)
+	}
+	return db.Where("username = ? AND `delete` = 0", login)
+}
+
+// checkEmailAvailable 邮箱不能是其他用户的邮箱或用户名，userId 为当前用户（注册时为 0）
+func checkEmailAvailable(db *gorm.DB, email string, userId int64) error {
+	var count int64
+	if err := db.Model(&model.User{}).Where("(email = ? OR username = ?) AND id <> ?", email, email, userId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("邮箱已被使用")
 	}
+	return nil
+}
 
-	return token, nil
+// rehashPassword 使用当前配置的算法重新哈希密码；条件更新避免覆盖并发修改的新密码
+func (s *UserService) rehashPassword(user *model.User, password string) {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return
+	}
+	application.GetDB().Model(&model.User{}).
+		Where("id = ? AND password = ?", user.ID, user.Password).
+		Update("password", hashedPassword)
 }
 
 // GetUserList 获取用户列表
@@ -176,12 +278,26 @@ func (s *UserService) UpdateUser(id int64, req *model.UpdateUserRequest) (*model
 		updates["nike_name"] = req.NikeName
 	}
 	if req.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		managed, err := ldapManaged(tx, id)
 		if err != nil {
 			tx.Rollback()
 			return nil, err
 		}
-		updates["password"] = string(hashedPassword)
+		if managed {
+			tx.Rollback()
+			return nil, ErrLDAPManagedPassword
+		}
+		if err := utils.ValidatePassword(user.Username, req.Password); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		hashedPassword, err := utils.HashPassword(req.Password)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		updates["password"] = hashedPassword
+		updates["password_change_time"] = time.Now()
 	}
 
 	if len(updates) > 0 {
@@ -195,6 +311,13 @@ func (s *UserService) UpdateUser(id int64, req *model.UpdateUserRequest) (*model
 		return nil, err
 	}
 
+	// 密码被重置后，此前签发的令牌全部失效
+	if req.Password != "" {
+		if err := (&TokenService{}).RevokeAllSessions(id); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := db.Where("id = ?", id).First(&user).Error; err != nil {
 		return nil, err
 	}
@@ -202,7 +325,66 @@ func (s *UserService) UpdateUser(id int64, req *model.UpdateUserRequest) (*model
 	return user.ToResponse(), nil
 }
 
-// DeleteUser 删除用户（软删除）
+// ChangePassword 修改密码：校验旧密码和密码策略，记录修改时间，并使此前签发的全部令牌失效
+// 返回新的令牌对，当前客户端可以继续使用而无需重新登录
+func (s *UserService) ChangePassword(id int64, req *model.ChangePasswordRequest, client *model.ClientInfo) (*model.TokenResponse, error) {
+	db := application.GetDB()
+	rdb := application.GetRedis()
+	ctx := context.Background()
+
+	lock := utils.NewRedisLock(rdb, fmt.Sprintf("update:user:%d", id), 10*time.Second)
+	if err := lock.TryLock(ctx, 1, 100*time.Millisecond); err != nil {
+		if errors.Is(err, utils.ErrLockFailed) {
+			return nil, errors.New("系统繁忙，请稍后重试")
+		}
+		return nil, err
+	}
+	defer lock.Unlock(ctx)
+
+	var user model.User
+	if err := db.Where("id = ? AND `delete` = 0", id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+
+	if managed, err := ldapManaged(db, id); err != nil {
+		return nil, err
+	} else if managed {
+		return nil, ErrLDAPManagedPassword
+	}
+	if ok, _, _ := utils.VerifyPassword(user.Password, req.OldPassword); !ok {
+		return nil, errors.New("旧密码错误")
+	}
+	if req.NewPassword == req.OldPassword {
+		return nil, errors.New("新密码不能与旧密码相同")
+	}
+	if err := utils.ValidatePassword(user.Username, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(&user).Updates(map[string]interface{}{
+		"password":             hashedPassword,
+		"password_change_time": time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	tokenService := &TokenService{}
+	if err := tokenService.RevokeAllSessions(id); err != nil {
+		return nil, err
+	}
+
+	return tokenService.IssueTokenPair(user.ID, user.Username, client)
+}
+
+// DeleteUser 删除用户（软删除），同时吊销全部会话
 func (s *UserService) DeleteUser(id int64) error {
 	db := application.GetDB()
 
@@ -218,5 +400,11 @@ func (s *UserService) DeleteUser(id int64) error {
 		return err
 	}
 
-	return nil
+	// 管理员删除和本人删除一样，已登录的会话和刷新令牌立即失效
+	return (&TokenService{}).RevokeAllSessions(id)
+}
+
+// Deactivate 注销当前账号，与删除用户相同
+func (s *UserService) Deactivate(id int64) error {
+	return s.DeleteUser(id)
 }
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+	"users-by-go-example/internal/model"
+	"users-by-go-example/internal/testenv"
+	"users-by-go-example/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteUser_RevokesSessions(t *testing.T) {
+	env := testenv.Setup(t, testenv.Config(t))
+	tokenService := &TokenService{}
+	user := env.CreateUser(t, "alice")
+
+	resp, err := tokenService.IssueTokenPair(user.ID, user.Username, &model.ClientInfo{})
+	require.NoError(t, err)
+	claims, err := utils.ParseToken(resp.Token)
+	require.NoError(t, err)
+
+	// 删除用户后访问令牌和刷新令牌立即失效，不依赖中间件重新加载用户
+	require.NoError(t, (&UserService{}).DeleteUser(user.ID))
+	assert.ErrorIs(t, tokenService.CheckAccessToken(claims), ErrTokenRevoked)
+	_, err = tokenService.Refresh(resp.RefreshToken)
+	assert.Error(t, err)
+
+	sessions, err := (&SessionService{}).List(user.ID, "")
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -0,0 +1,125 @@
+// Package testenv 为服务和中间件的测试提供内存 Redis（miniredis）和临时 SQLite 数据库
+package testenv
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/config"
+	"users-by-go-example/internal/mailer"
+	"users-by-go-example/internal/model"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Env 测试环境
+type Env struct {
+	Config *config.Config
+	DB     *gorm.DB
+	Redis  *miniredis.Miniredis
+	Mailer *Mailer
+}
+
+// Mailer 记录发送的邮件，不真正发送
+type Mailer struct {
+	mu       sync.Mutex
+	Messages []*mailer.Message
+}
+
+// Send 记录邮件
+func (m *Mailer) Send(msg *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, msg)
+	return nil
+}
+
+// Last 最近发送的一封邮件，没有时返回 nil
+func (m *Mailer) Last() *mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Messages) == 0 {
+		return nil
+	}
+	return m.Messages[len(m.Messages)-1]
+}
+
+// Config 加载仓库中的 config.yml，测试可在 Setup 前按需修改
+func Config(t *testing.T) *config.Config {
+	t.Helper()
+	_, file, _, _ := runtime.Caller(0)
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(file), "..", "config", "config.yml"))
+	if err != nil {
+		t.Fatalf("读取配置失败: %v", err)
+	}
+	var conf config.Config
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		t.Fatalf("解析配置失败: %v", err)
+	}
+	return &conf
+}
+
+// Setup 创建空数据库和 Redis 并注入 application，测试结束时自动清理
+func Setup(t *testing.T, conf *config.Config) *Env {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000&_journal_mode=WAL"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(
+		&model.User{},
+		&model.Permission{},
+		&model.UserPermission{},
+		&model.Role{},
+		&model.RolePermission{},
+		&model.UserRole{},
+		&model.RecoveryCode{},
+		&model.ApiKey{},
+		&model.UserIdentity{},
+		&model.OAuthClient{},
+		&model.Passkey{},
+		&model.ImpersonationLog{},
+	); err != nil {
+		t.Fatalf("创建表失败: %v", err)
+	}
+
+	m := &Mailer{}
+	application.Setup(conf, db, rdb, m)
+	return &Env{Config: conf, DB: db, Redis: mr, Mailer: m}
+}
+
+// CreateUser 创建测试用户，permits 直接授予该用户
+func (e *Env) CreateUser(t *testing.T, username string, permits ...string) *model.User {
+	t.Helper()
+	user := &model.User{Username: username, Password: "-"}
+	if err := e.DB.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	for _, item := range permits {
+		var permission model.Permission
+		if err := e.DB.Where(model.Permission{Permit: item}).FirstOrCreate(&permission).Error; err != nil {
+			t.Fatalf("创建权限失败: %v", err)
+		}
+		if err := e.DB.Create(&model.UserPermission{UserId: user.ID, PermissionId: permission.ID}).Error; err != nil {
+			t.Fatalf("授权失败: %v", err)
+		}
+	}
+	return user
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 	app "users-by-go-example/internal/application"
 	"users-by-go-example/internal/router"
+	"users-by-go-example/utils"
 )
 
 func main() {
@@ -21,6 +22,21 @@ func main() {
 	// 延迟关闭资源
 	defer app.Close()
 
+	// 加载 JWT 签名密钥
+	if err := utils.InitJWTKeys(); err != nil {
+		log.Fatalf("JWT 密钥加载失败: %v", err)
+	}
+
+	// 加载密码策略和密码黑名单
+	if err := utils.InitPasswordPolicy(); err != nil {
+		log.Fatalf("密码策略加载失败: %v", err)
+	}
+
+	// 初始化密码哈希算法
+	if err := utils.InitPasswordHasher(); err != nil {
+		log.Fatalf("密码哈希配置错误: %v", err)
+	}
+
 	// 设置路由
 	r := router.SetupRouter()
 
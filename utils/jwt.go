@@ -2,6 +2,7 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"time"
 	"users-by-go-example/internal/application"
 
@@ -10,43 +11,142 @@ import (
 
 // Claims JWT 声明
 type Claims struct {
-	UserID   int64  `json:"userId"`
-	Username string `json:"username"`
+	UserID     int64  `json:"userId"`
+	Username   string `json:"username"`
+	Generation int64  `json:"gen"`           // 签发时用户的令牌代数，小于当前代数的令牌视为已吊销
+	SessionID  string `json:"sid,omitempty"` // 所属会话（即刷新令牌家族），会话被吊销后令牌随之失效
+	Actor      *Actor `json:"act,omitempty"` // 模拟登录时的真实操作人，UserID 为被模拟的用户
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成 JWT token
-func GenerateToken(userID int64, username string) (string, error) {
-	conf := application.GetConfig()
-	expireTime := time.Duration(conf.JWT.ExpireTime) * time.Hour
+// Actor 模拟登录的真实操作人（RFC 8693 act 声明）
+type Actor struct {
+	UserID     int64  `json:"userId"`
+	Username   string `json:"username"`
+	Generation int64  `json:"gen"` // 签发时操作人的令牌代数，操作人被吊销全部会话时模拟令牌随之失效
+}
+
+// GenerateToken 生成 JWT token，jti 与有效期等标准声明在此统一填充
+func GenerateToken(userID int64, username string, generation int64, sessionID string) (string, error) {
+	expireTime := AccessTokenTTL()
+
+	jti, err := RandomToken(16)
+	if err != nil {
+		return "", err
+	}
 
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:     userID,
+		Username:   username,
+		Generation: generation,
+		SessionID:  sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expireTime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(conf.JWT.Secret))
+	return SignJWT(claims)
+}
+
+// GenerateImpersonationToken 生成模拟登录令牌：以目标用户身份访问，act 声明记录真实操作人，不关联会话，不能刷新
+func GenerateImpersonationToken(userID int64, username string, generation int64, actor *Actor, ttl time.Duration) (string, string, error) {
+	jti, err := RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := Claims{
+		UserID:     userID,
+		Username:   username,
+		Generation: generation,
+		Actor:      actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token, err := SignJWT(claims)
+	if err != nil {
+		return "", "", err
+	}
+	return token, jti, nil
+}
+
+// SignJWT 使用当前签名密钥签发任意声明，未配置非对称密钥时使用 HS256 共享密钥
+func SignJWT(claims jwt.Claims) (string, error) {
+	if activeJWTKey == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(application.GetConfig().JWT.Secret))
+	}
+
+	token := jwt.NewWithClaims(activeJWTKey.method, claims)
+	token.Header["kid"] = activeJWTKey.kid
+	return token.SignedString(activeJWTKey.privateKey)
+}
+
+// 未配置有效期时的默认值，旧配置中的 expire-time 已改名，读到 0 时不能签发立即过期的令牌
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 168 * time.Hour
+)
+
+// AccessTokenTTL 访问令牌有效期
+func AccessTokenTTL() time.Duration {
+	ttl := time.Duration(application.GetConfig().JWT.AccessExpireTime) * time.Minute
+	if ttl <= 0 {
+		return defaultAccessTokenTTL
+	}
+	return ttl
+}
+
+// RefreshTokenTTL 刷新令牌有效期
+func RefreshTokenTTL() time.Duration {
+	ttl := time.Duration(application.GetConfig().JWT.RefreshExpireTime) * time.Hour
+	if ttl <= 0 {
+		return defaultRefreshTokenTTL
+	}
+	return ttl
 }
 
 // ParseToken 解析 JWT token
 func ParseToken(tokenString string) (*Claims, error) {
-	cfg := application.GetConfig()
-
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(cfg.JWT.Secret), nil
-	})
+	var token *jwt.Token
+	var err error
+	if activeJWTKey == nil {
+		secret := application.GetConfig().JWT.Secret
+		token, err = jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	} else {
+		// 按 kid 查找验签公钥，轮换期间新旧密钥签发的 token 都能通过验证
+		token, err = jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			key, ok := jwtKeys[kid]
+			if !ok {
+				return nil, fmt.Errorf("unknown kid: %q", kid)
+			}
+			if token.Method.Alg() != key.method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+			}
+			return key.publicKey, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
+	}
 
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		// 签发给第三方应用的 ID Token 带有 aud，不能当作访问令牌使用
+		if len(claims.Audience) > 0 {
+			return nil, errors.New("invalid token")
+		}
 		return claims, nil
 	}
 
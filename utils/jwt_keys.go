@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtKey 一把 JWT 签名密钥，只配置公钥时仅用于验签（已轮换下线的旧密钥）
+type jwtKey struct {
+	kid        string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// JSONWebKey JWKS 中的单个公钥（RFC 7517）
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // OKP 曲线
+	X   string `json:"x,omitempty"`   // OKP 公钥
+}
+
+// JSONWebKeySet 公钥集合
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var (
+	jwtKeys       map[string]*jwtKey // kid -> 密钥
+	jwtKeyList    []*jwtKey          // 按配置顺序排列，用于输出 JWKS
+	activeJWTKey  *jwtKey            // 当前用于签名的密钥，为 nil 时使用 HS256 共享密钥
+	loadKeysOnce  sync.Once
+	loadKeysError error
+)
+
+// InitJWTKeys 加载 JWT 非对称签名密钥，未配置密钥时沿用 HS256 共享密钥
+func InitJWTKeys() error {
+	loadKeysOnce.Do(func() {
+		loadKeysError = loadJWTKeys(&application.GetConfig().JWT)
+	})
+	return loadKeysError
+}
+
+func loadJWTKeys(conf *config.JWTConfig) error {
+	jwtKeys = make(map[string]*jwtKey)
+	jwtKeyList = nil
+	activeJWTKey = nil
+	if len(conf.Keys) == 0 {
+		return nil
+	}
+
+	for _, item := range conf.Keys {
+		if item.Kid == "" {
+			return errors.New("JWT 密钥缺少 kid")
+		}
+		if _, exists := jwtKeys[item.Kid]; exists {
+			return fmt.Errorf("JWT 密钥 kid 重复: %s", item.Kid)
+		}
+
+		key, err := loadJWTKey(item)
+		if err != nil {
+			return fmt.Errorf("加载 JWT 密钥 %s 失败: %w", item.Kid, err)
+		}
+		jwtKeys[item.Kid] = key
+		jwtKeyList = append(jwtKeyList, key)
+	}
+
+	active, ok := jwtKeys[conf.SigningKid]
+	if !ok {
+		return fmt.Errorf("签名密钥 %q 未在 keys 中配置", conf.SigningKid)
+	}
+	if active.privateKey == nil {
+		return fmt.Errorf("签名密钥 %s 缺少私钥", active.kid)
+	}
+	activeJWTKey = active
+
+	return nil
+}
+
+func loadJWTKey(item config.JWTKeyConfig) (*jwtKey, error) {
+	key := &jwtKey{kid: item.Kid}
+
+	switch item.Algorithm {
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("不支持的签名算法: %s", item.Algorithm)
+	}
+
+	if item.PrivateKey != "" {
+		block, err := readPEM(item.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			// 兼容 openssl genrsa 生成的 PKCS#1 格式
+			if privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+				return nil, fmt.Errorf("解析私钥失败: %w", err)
+			}
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("私钥类型不支持签名")
+		}
+		key.privateKey = signer
+		key.publicKey = signer.Public()
+	}
+
+	if item.PublicKey != "" {
+		block, err := readPEM(item.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析公钥失败: %w", err)
+		}
+		// 同时配置私钥和公钥时必须是同一对密钥，否则签发的令牌无法通过 JWKS 验签
+		if key.privateKey != nil {
+			pub, ok := key.privateKey.Public().(interface{ Equal(crypto.PublicKey) bool })
+			if !ok || !pub.Equal(publicKey) {
+				return nil, errors.New("私钥与公钥不匹配")
+			}
+		}
+		key.publicKey = publicKey
+	}
+
+	if key.publicKey == nil {
+		return nil, errors.New("未配置私钥或公钥")
+	}
+
+	switch key.publicKey.(type) {
+	case *rsa.PublicKey:
+		if key.method != jwt.SigningMethodRS256 {
+			return nil, errors.New("密钥类型与算法不匹配")
+		}
+	case ed25519.PublicKey:
+		if key.method != jwt.SigningMethodEdDSA {
+			return nil, errors.New("密钥类型与算法不匹配")
+		}
+	default:
+		return nil, errors.New("不支持的密钥类型")
+	}
+
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s 不是有效的 PEM 文件", path)
+	}
+	return block, nil
+}
+
+// SigningAlg 当前非对称签名算法，只使用 HS256 共享密钥时返回空字符串
+func SigningAlg() string {
+	if activeJWTKey == nil {
+		return ""
+	}
+	return activeJWTKey.method.Alg()
+}
+
+// GetJWKS 返回所有已配置密钥的公钥，供其他服务离线验签
+func GetJWKS() *JSONWebKeySet {
+	set := &JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(jwtKeyList))}
+	for _, key := range jwtKeyList {
+		jwk := JSONWebKey{
+			Use: "sig",
+			Kid: key.kid,
+			Alg: key.method.Alg(),
+		}
+		switch pub := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
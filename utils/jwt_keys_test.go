@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"users-by-go-example/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair 把密钥对写成 PEM 文件，返回私钥和公钥路径
+func writeKeyPair(t *testing.T, name string, signer crypto.Signer) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	require.NoError(t, err)
+	privatePath := filepath.Join(dir, name+".pem")
+	require.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	der, err = x509.MarshalPKIXPublicKey(signer.Public())
+	require.NoError(t, err)
+	publicPath := filepath.Join(dir, name+".pub.pem")
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	return privatePath, publicPath
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+// resetJWTKeys 测试结束后恢复为未配置密钥的状态，避免影响其他测试
+func resetJWTKeys(t *testing.T) {
+	t.Cleanup(func() {
+		jwtKeys, jwtKeyList, activeJWTKey = nil, nil, nil
+	})
+}
+
+func testClaims(userID int64) *Claims {
+	return &Claims{
+		UserID:   userID,
+		Username: "alice",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func TestLoadJWTKeys(t *testing.T) {
+	resetJWTKeys(t)
+	edPrivate, _ := writeKeyPair(t, "ed", newEd25519Key(t))
+	rsaPrivate, rsaPublic := writeKeyPair(t, "rsa", newRSAKey(t))
+
+	err := loadJWTKeys(&config.JWTConfig{
+		SigningKid: "ed-1",
+		Keys: []config.JWTKeyConfig{
+			{Kid: "ed-1", Algorithm: "EdDSA", PrivateKey: edPrivate},
+			{Kid: "rsa-1", Algorithm: "RS256", PrivateKey: rsaPrivate, PublicKey: rsaPublic},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", SigningAlg())
+
+	token, err := SignJWT(testClaims(7))
+	require.NoError(t, err)
+	claims, err := ParseToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), claims.UserID)
+}
+
+func TestLoadJWTKeys_Invalid(t *testing.T) {
+	resetJWTKeys(t)
+	edPrivate, edPublic := writeKeyPair(t, "ed", newEd25519Key(t))
+	_, otherPublic := writeKeyPair(t, "other", newEd25519Key(t))
+	_, rsaPublic := writeKeyPair(t, "rsa", newRSAKey(t))
+
+	cases := map[string]config.JWTConfig{
+		"缺少 kid": {SigningKid: "ed-1", Keys: []config.JWTKeyConfig{
+			{Algorithm: "EdDSA", PrivateKey: edPrivate},
+		}},
+		"kid 重复": {SigningKid: "ed-1", Keys: []config.JWTKeyConfig{
+			{Kid: "ed-1", Algorithm: "EdDSA", PrivateKey: edPrivate},
+			{Kid: "ed-1", Algorithm: "EdDSA", PublicKey: edPublic},
+		}},
+		"私钥与公钥不匹配": {SigningKid: "ed-1", Keys: []config.JWTKeyConfig{
+			{Kid: "ed-1", Algorithm: "EdDSA", PrivateKey: edPrivate, PublicKey: otherPublic},
+		}},
+		"密钥类型与算法不匹配": {SigningKid: "rsa-1", Keys: []config.JWTKeyConfig{
+			{Kid: "rsa-1", Algorithm: "EdDSA", PublicKey: rsaPublic},
+		}},
+		"签名密钥只有公钥": {SigningKid: "ed-1", Keys: []config.JWTKeyConfig{
+			{Kid: "ed-1", Algorithm: "EdDSA", PublicKey: edPublic},
+		}},
+		"签名密钥未配置": {SigningKid: "missing", Keys: []config.JWTKeyConfig{
+			{Kid: "ed-1", Algorithm: "EdDSA", PrivateKey: edPrivate},
+		}},
+	}
+	for name, conf := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, loadJWTKeys(&conf))
+		})
+	}
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	resetJWTKeys(t)
+	oldKey := newRSAKey(t)
+	oldPrivate, oldPublic := writeKeyPair(t, "old", oldKey)
+	newPrivate, _ := writeKeyPair(t, "new", newEd25519Key(t))
+
+	require.NoError(t, loadJWTKeys(&config.JWTConfig{
+		SigningKid: "rsa-old",
+		Keys: []config.JWTKeyConfig{
+			{Kid: "rsa-old", Algorithm: "RS256", PrivateKey: oldPrivate},
+		},
+	}))
+	oldToken, err := SignJWT(testClaims(1))
+	require.NoError(t, err)
+
+	// 切换签名密钥后旧密钥只保留公钥，轮换前签发的令牌仍能验签
+	require.NoError(t, loadJWTKeys(&config.JWTConfig{
+		SigningKid: "ed-new",
+		Keys: []config.JWTKeyConfig{
+			{Kid: "ed-new", Algorithm: "EdDSA", PrivateKey: newPrivate},
+			{Kid: "rsa-old", Algorithm: "RS256", PublicKey: oldPublic},
+		},
+	}))
+	newToken, err := SignJWT(testClaims(2))
+	require.NoError(t, err)
+
+	claims, err := ParseToken(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), claims.UserID)
+	claims, err = ParseToken(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), claims.UserID)
+
+	// 旧密钥彻底下线后，用它签发的令牌不再被接受
+	require.NoError(t, loadJWTKeys(&config.JWTConfig{
+		SigningKid: "ed-new",
+		Keys: []config.JWTKeyConfig{
+			{Kid: "ed-new", Algorithm: "EdDSA", PrivateKey: newPrivate},
+		},
+	}))
+	_, err = ParseToken(oldToken)
+	assert.Error(t, err)
+
+	// JWKS 只公开公钥，RSA 参数与密钥一致
+	require.NoError(t, loadJWTKeys(&config.JWTConfig{
+		SigningKid: "ed-new",
+		Keys: []config.JWTKeyConfig{
+			{Kid: "ed-new", Algorithm: "EdDSA", PrivateKey: newPrivate},
+			{Kid: "rsa-old", Algorithm: "RS256", PublicKey: oldPublic},
+		},
+	}))
+	jwks := GetJWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, JSONWebKey{
+		Kty: "OKP", Use: "sig", Kid: "ed-new", Alg: "EdDSA", Crv: "Ed25519",
+		X: jwks.Keys[0].X,
+	}, jwks.Keys[0])
+	x, err := base64.RawURLEncoding.DecodeString(jwks.Keys[0].X)
+	require.NoError(t, err)
+	assert.Len(t, x, ed25519.PublicKeySize)
+
+	rsaJWK := jwks.Keys[1]
+	assert.Equal(t, "RSA", rsaJWK.Kty)
+	assert.Equal(t, "RS256", rsaJWK.Alg)
+	assert.Equal(t, "rsa-old", rsaJWK.Kid)
+	n, err := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	require.NoError(t, err)
+	assert.Equal(t, 0, new(big.Int).SetBytes(n).Cmp(oldKey.N))
+	assert.Equal(t, "AQAB", rsaJWK.E)
+}
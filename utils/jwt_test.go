@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"testing"
+	"time"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateImpersonationToken(t *testing.T) {
+	resetJWTKeys(t)
+	privatePath, _ := writeKeyPair(t, "ed", newEd25519Key(t))
+	require.NoError(t, loadJWTKeys(&config.JWTConfig{
+		SigningKid: "ed-1",
+		Keys:       []config.JWTKeyConfig{{Kid: "ed-1", Algorithm: "EdDSA", PrivateKey: privatePath}},
+	}))
+
+	actor := &Actor{UserID: 1, Username: "admin", Generation: 3}
+	token, jti, err := GenerateImpersonationToken(2, "alice", 5, actor, 10*time.Minute)
+	require.NoError(t, err)
+
+	// 令牌以被模拟的用户为主体，act 声明保留真实操作人及其令牌代数
+	claims, err := ParseToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), claims.UserID)
+	assert.Equal(t, "alice", claims.Username)
+	assert.Equal(t, int64(5), claims.Generation)
+	assert.Equal(t, actor, claims.Actor)
+	assert.Equal(t, jti, claims.ID)
+	assert.NotEmpty(t, jti)
+	// 模拟令牌不属于任何会话，不能刷新
+	assert.Empty(t, claims.SessionID)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), claims.ExpiresAt.Time, 5*time.Second)
+
+	// 每次签发的 jti 不同，审计记录可以区分
+	_, other, err := GenerateImpersonationToken(2, "alice", 5, actor, 10*time.Minute)
+	require.NoError(t, err)
+	assert.NotEqual(t, jti, other)
+
+	// 过期后不再被接受
+	expired, _, err := GenerateImpersonationToken(2, "alice", 5, actor, -time.Minute)
+	require.NoError(t, err)
+	_, err = ParseToken(expired)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+}
+
+func TestTokenTTL_Defaults(t *testing.T) {
+	// 旧配置只有 expire-time，新字段读到 0 时使用默认有效期
+	application.Setup(&config.Config{}, nil, nil, nil)
+	assert.Equal(t, 15*time.Minute, AccessTokenTTL())
+	assert.Equal(t, 168*time.Hour, RefreshTokenTTL())
+
+	application.Setup(&config.Config{JWT: config.JWTConfig{AccessExpireTime: 5, RefreshExpireTime: 24}}, nil, nil, nil)
+	assert.Equal(t, 5*time.Minute, AccessTokenTTL())
+	assert.Equal(t, 24*time.Hour, RefreshTokenTTL())
+}
@@ -0,0 +1,233 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnknownHashFormat = errors.New("无法识别的密码哈希格式")
+
+// PasswordHasher 密码哈希算法，哈希结果为 PHC 格式字符串（$算法$参数$盐$哈希）
+type PasswordHasher interface {
+	// Hash 生成密码哈希
+	Hash(password string) (string, error)
+	// Verify 校验密码，参数从 encoded 中解析，与当前配置无关
+	Verify(encoded, password string) (bool, error)
+	// NeedsRehash encoded 的算法或参数与当前配置不一致时返回 true
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idHasher argon2id 哈希，格式：$argon2id$v=19$m=65536,t=3,p=2$<盐>$<哈希>
+type Argon2idHasher struct {
+	Memory      uint32 // 内存（KiB）
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// argon2idParams 从哈希字符串中解析出的参数
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+// argon2Semaphore 限制同时进行的 argon2id 计算数，每个计算都要占用 Memory 大小的内存
+var argon2Semaphore = make(chan struct{}, runtime.NumCPU())
+
+// argon2idKey 获取信号量后计算 argon2id 哈希，超出并发上限时排队等待
+func argon2idKey(password, salt []byte, iterations, memory uint32, parallelism uint8, keyLength uint32) []byte {
+	argon2Semaphore <- struct{}{}
+	defer func() { <-argon2Semaphore }()
+	return argon2.IDKey(password, salt, iterations, memory, parallelism, keyLength)
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2idKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	p, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2idKey([]byte(password), p.salt, p.iterations, p.memory, p.parallelism, uint32(len(p.key)))
+	return subtle.ConstantTimeCompare(key, p.key) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	p, err := parseArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return p.memory != h.Memory || p.iterations != h.Iterations || p.parallelism != h.Parallelism ||
+		uint32(len(p.salt)) != h.SaltLength || uint32(len(p.key)) != h.KeyLength
+}
+
+func parseArgon2id(encoded string) (*argon2idParams, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", 盐, 哈希
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("不支持的 argon2 版本: %s", parts[2])
+	}
+
+	p := &argon2idParams{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return nil, fmt.Errorf("argon2 参数格式错误: %w", err)
+	}
+
+	var err error
+	if p.salt, err = phcEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("argon2 盐格式错误: %w", err)
+	}
+	if p.key, err = phcEncoding.DecodeString(parts[5]); err != nil {
+		return nil, fmt.Errorf("argon2 哈希格式错误: %w", err)
+	}
+	return p, nil
+}
+
+// bcryptMaxPasswordBytes bcrypt 只使用密码的前 72 个字节
+const bcryptMaxPasswordBytes = 72
+
+// BcryptHasher bcrypt 哈希，格式：$2a$10$<盐和哈希>
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	return string(hashed), err
+}
+
+func (h *BcryptHasher) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.Cost
+}
+
+// NewPasswordHasher 按配置创建哈希算法
+func NewPasswordHasher(conf *config.PasswordHashConfig) (PasswordHasher, error) {
+	switch conf.Algorithm {
+	case "", "argon2id":
+		a := conf.Argon2id
+		if a.Memory == 0 || a.Iterations == 0 || a.Parallelism == 0 || a.SaltLength == 0 || a.KeyLength == 0 {
+			return nil, errors.New("argon2id 参数必须大于 0")
+		}
+		if a.MaxConcurrency < 0 {
+			return nil, errors.New("argon2id max-concurrency 不能小于 0")
+		}
+		return &Argon2idHasher{
+			Memory:      a.Memory,
+			Iterations:  a.Iterations,
+			Parallelism: a.Parallelism,
+			SaltLength:  a.SaltLength,
+			KeyLength:   a.KeyLength,
+		}, nil
+	case "bcrypt":
+		cost := conf.Bcrypt.Cost
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost 必须在 %d 到 %d 之间", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return &BcryptHasher{Cost: cost}, nil
+	default:
+		return nil, fmt.Errorf("不支持的密码哈希算法: %s", conf.Algorithm)
+	}
+}
+
+var (
+	passwordHasher  PasswordHasher = &BcryptHasher{Cost: bcrypt.DefaultCost}
+	loadHasherOnce  sync.Once
+	loadHasherError error
+
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// InitPasswordHasher 按配置初始化全局密码哈希算法，未初始化时使用 bcrypt 默认参数
+func InitPasswordHasher() error {
+	loadHasherOnce.Do(func() {
+		var hasher PasswordHasher
+		conf := &application.GetConfig().PasswordHash
+		hasher, loadHasherError = NewPasswordHasher(conf)
+		if loadHasherError == nil {
+			passwordHasher = hasher
+		}
+		if conf.Argon2id.MaxConcurrency > 0 {
+			argon2Semaphore = make(chan struct{}, conf.Argon2id.MaxConcurrency)
+		}
+	})
+	return loadHasherError
+}
+
+// HashPassword 使用当前配置的算法生成密码哈希
+func HashPassword(password string) (string, error) {
+	return passwordHasher.Hash(password)
+}
+
+// VerifyPassword 按哈希格式选择算法校验密码；needsRehash 表示校验通过但哈希的算法或参数已过时，应使用当前配置重新哈希
+func VerifyPassword(encoded, password string) (ok, needsRehash bool, err error) {
+	var hasher PasswordHasher
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		hasher = &Argon2idHasher{}
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		hasher = &BcryptHasher{}
+	default:
+		return false, false, ErrUnknownHashFormat
+	}
+
+	ok, err = hasher.Verify(encoded, password)
+	if err != nil || !ok {
+		return false, false, err
+	}
+	return true, passwordHasher.NeedsRehash(encoded), nil
+}
+
+// VerifyDummyPassword 用户不存在时按当前算法校验一个固定哈希，使耗时与用户存在时一致，避免通过响应时间探测账号是否存在
+func VerifyDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = HashPassword("dummy-password")
+	})
+	VerifyPassword(dummyHash, password)
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试使用较小的参数，避免拖慢测试
+func testArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	h := testArgon2idHasher()
+
+	encoded, err := h.Hash("secret2026")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	ok, err := h.Verify(encoded, "secret2026")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = h.Verify(encoded, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// 相同密码每次使用不同的盐
+	another, _ := h.Hash("secret2026")
+	assert.NotEqual(t, encoded, another)
+
+	assert.False(t, h.NeedsRehash(encoded))
+	stronger := testArgon2idHasher()
+	stronger.Iterations = 2
+	assert.True(t, stronger.NeedsRehash(encoded))
+}
+
+func TestArgon2idHasher_Concurrency(t *testing.T) {
+	original := argon2Semaphore
+	argon2Semaphore = make(chan struct{}, 1)
+	t.Cleanup(func() { argon2Semaphore = original })
+
+	// 占满信号量后新的计算只能排队
+	argon2Semaphore <- struct{}{}
+	done := make(chan struct{})
+	go func() {
+		testArgon2idHasher().Hash("secret2026")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("超出并发上限的计算没有等待")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	<-argon2Semaphore
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("释放信号量后计算没有继续")
+	}
+}
+
+func TestArgon2idHasher_Malformed(t *testing.T) {
+	h := testArgon2idHasher()
+	for _, encoded := range []string{
+		"",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5",
+	} {
+		_, err := h.Verify(encoded, "secret2026")
+		assert.Error(t, err, encoded)
+		assert.True(t, h.NeedsRehash(encoded), encoded)
+	}
+}
+
+func TestVerifyPassword_Migration(t *testing.T) {
+	old := passwordHasher
+	defer func() { passwordHasher = old }()
+
+	bcryptHash, err := (&BcryptHasher{Cost: 4}).Hash("secret2026")
+	assert.NoError(t, err)
+
+	// 当前算法为 argon2id：旧的 bcrypt 哈希能校验通过，并提示需要重新哈希
+	passwordHasher = testArgon2idHasher()
+	ok, rehash, err := VerifyPassword(bcryptHash, "secret2026")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	ok, rehash, err = VerifyPassword(bcryptHash, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, rehash)
+
+	argonHash, err := HashPassword("secret2026")
+	assert.NoError(t, err)
+	ok, rehash, err = VerifyPassword(argonHash, "secret2026")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	_, _, err = VerifyPassword("plain-text", "plain-text")
+	assert.ErrorIs(t, err, ErrUnknownHashFormat)
+}
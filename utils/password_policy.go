@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+	"users-by-go-example/internal/application"
+	"users-by-go-example/internal/config"
+)
+
+// PasswordViolation 一条不满足的密码规则
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError 密码不满足策略，Violations 列出全部不满足的规则
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "密码不符合要求: " + strings.Join(messages, "；")
+}
+
+// PasswordPolicy 密码策略
+type PasswordPolicy struct {
+	conf      config.PasswordPolicyConfig
+	blocklist map[string]struct{} // 常见或已泄露的密码，统一转小写
+}
+
+var (
+	passwordPolicy      *PasswordPolicy
+	loadPolicyOnce      sync.Once
+	loadPolicyError     error
+	defaultPolicyConfig = config.PasswordPolicyConfig{MinLength: 6, MaxLength: 50, DisallowUsername: true}
+)
+
+// InitPasswordPolicy 按配置加载密码策略和密码黑名单
+func InitPasswordPolicy() error {
+	loadPolicyOnce.Do(func() {
+		passwordPolicy, loadPolicyError = NewPasswordPolicy(&application.GetConfig().PasswordPolicy)
+	})
+	return loadPolicyError
+}
+
+// ValidatePassword 使用全局密码策略校验密码，未初始化时使用默认策略
+func ValidatePassword(username, password string) error {
+	policy := passwordPolicy
+	if policy == nil {
+		policy = &PasswordPolicy{conf: defaultPolicyConfig}
+	}
+	return policy.Validate(username, password)
+}
+
+// NewPasswordPolicy 创建密码策略，配置了 blocklist-file 时加载黑名单（每行一个密码，# 开头为注释）
+func NewPasswordPolicy(conf *config.PasswordPolicyConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{conf: *conf}
+	if conf.BlocklistFile == "" {
+		return policy, nil
+	}
+
+	file, err := os.Open(conf.BlocklistFile)
+	if err != nil {
+		return nil, fmt.Errorf("密码黑名单加载失败: %w", err)
+	}
+	defer file.Close()
+
+	policy.blocklist = make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		policy.blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("密码黑名单加载失败: %w", err)
+	}
+	return policy, nil
+}
+
+// Validate 校验密码，不满足时返回 *PasswordPolicyError
+func (p *PasswordPolicy) Validate(username, password string) error {
+	var violations []PasswordViolation
+	add := func(rule, format string, args ...any) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if p.conf.MinLength > 0 && length < p.conf.MinLength {
+		add("min_length", "长度不能少于 %d 个字符", p.conf.MinLength)
+	}
+	if p.conf.MaxLength > 0 && length > p.conf.MaxLength {
+		add("max_length", "长度不能超过 %d 个字符", p.conf.MaxLength)
+	}
+	// 使用 bcrypt 时超出的部分不参与哈希，按字节而不是字符计算，中文等多字节字符会更早达到上限
+	if _, ok := passwordHasher.(*BcryptHasher); ok && len(password) > bcryptMaxPasswordBytes {
+		add("max_bytes", "长度不能超过 %d 个字节", bcryptMaxPasswordBytes)
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+	if p.conf.RequireLower && !hasLower {
+		add("require_lower", "必须包含小写字母")
+	}
+	if p.conf.RequireUpper && !hasUpper {
+		add("require_upper", "必须包含大写字母")
+	}
+	if p.conf.RequireDigit && !hasDigit {
+		add("require_digit", "必须包含数字")
+	}
+	if p.conf.RequireSymbol && !hasSymbol {
+		add("require_symbol", "必须包含特殊字符")
+	}
+
+	lower := strings.ToLower(password)
+	if p.conf.DisallowUsername && username != "" && strings.Contains(lower, strings.ToLower(username)) {
+		add("contains_username", "不能包含用户名")
+	}
+	if _, ok := p.blocklist[lower]; ok {
+		add("blocklisted", "密码过于常见或已泄露，请更换")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
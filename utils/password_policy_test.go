@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"users-by-go-example/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func violationRules(err error) []string {
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+	rules := make([]string, 0, len(policyErr.Violations))
+	for _, v := range policyErr.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy, err := NewPasswordPolicy(&config.PasswordPolicyConfig{
+		MinLength:        8,
+		MaxLength:        20,
+		RequireLower:     true,
+		RequireUpper:     true,
+		RequireDigit:     true,
+		DisallowUsername: true,
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     []string
+	}{
+		{"满足全部规则", "alice", "Secur3Pass", nil},
+		{"过短且缺少字符类型", "alice", "abc", []string{"min_length", "require_upper", "require_digit"}},
+		{"过长", "alice", "Abcdefgh1234567890xyz", []string{"max_length"}},
+		{"包含用户名忽略大小写", "alice", "99ALICE99", []string{"require_lower", "contains_username"}},
+		{"中文用户名", "张三", "Abc12345张三", []string{"contains_username"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, violationRules(policy.Validate(tt.username, tt.password)))
+		})
+	}
+}
+
+func TestPasswordPolicy_BcryptMaxBytes(t *testing.T) {
+	original := passwordHasher
+	t.Cleanup(func() { passwordHasher = original })
+
+	policy, err := NewPasswordPolicy(&config.PasswordPolicyConfig{MaxLength: 100})
+	assert.NoError(t, err)
+	// 30 个汉字为 90 字节，不超过字符数上限，但超出 bcrypt 的 72 字节
+	password := strings.Repeat("密", 30)
+
+	passwordHasher = &BcryptHasher{Cost: bcrypt.MinCost}
+	assert.Equal(t, []string{"max_bytes"}, violationRules(policy.Validate("bob", password)))
+	assert.NoError(t, policy.Validate("bob", strings.Repeat("a", 72)))
+
+	passwordHasher = testArgon2idHasher()
+	assert.NoError(t, policy.Validate("bob", password))
+}
+
+func TestPasswordPolicy_Blocklist(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "blocklist.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("# 注释\n123456\nPassword1\n\n"), 0o600))
+
+	policy, err := NewPasswordPolicy(&config.PasswordPolicyConfig{BlocklistFile: file})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"blocklisted"}, violationRules(policy.Validate("bob", "123456")))
+	assert.Equal(t, []string{"blocklisted"}, violationRules(policy.Validate("bob", "PASSWORD1")))
+	assert.NoError(t, policy.Validate("bob", "# 注释"))
+	assert.NoError(t, policy.Validate("bob", "correct horse battery"))
+}
+
+func TestNewPasswordPolicy_MissingBlocklist(t *testing.T) {
+	_, err := NewPasswordPolicy(&config.PasswordPolicyConfig{BlocklistFile: "not-exist.txt"})
+	assert.Error(t, err)
+}
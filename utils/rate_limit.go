@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AllowRequest 固定窗口限流：窗口内第 limit+1 次起返回 false，limit <= 0 表示不限制
+func AllowRequest(ctx context.Context, client *redis.Client, key string, limit int, window time.Duration) (bool, error) {
+	if limit <= 0 {
+		return true, nil
+	}
+
+	key = "rate_limit:" + key
+	count, err := client.Incr(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	// 窗口内第一次请求时设置过期时间
+	if count == 1 {
+		if err := client.Expire(ctx, key, window).Err(); err != nil {
+			return false, err
+		}
+	}
+
+	return count <= int64(limit), nil
+}